@@ -17,7 +17,11 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"os-diff/pkg/godiff"
+	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -27,28 +31,165 @@ var origin string
 var destination string
 var output string
 var reverse bool
+var patchFormat string
+var mergeKeys []string
+var typeOverrides []string
+var format string
+var failOn string
+var combinedPatch bool
+var ignoreFile string
+var showIgnored bool
+var mappingFile string
+var secretPatterns []string
+var showSecrets bool
+var severityFile string
+var minSeverity string
+var metadataDir string
+var originMetadataDir string
+var base string
+var jobs int
 
 var compareCmd = &cobra.Command{
 	Use:   "compare",
 	Short: "Compare two files or directories",
 	Long: `Compare files or directories from two different paths. For example:
-		os-diff compare --origin=tests/podman/keystone.conf --destination=tests/ocp/keystone.conf --output=output.txt`,
-	Run: func(cmd *cobra.Command, args []string) {
+		os-diff compare --origin=tests/podman/keystone.conf --destination=tests/ocp/keystone.conf --output=/tmp/os-diff-output`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := godiff.ValidateFailOn(failOn); err != nil {
+			return err
+		}
+		if err := godiff.ValidateFormat(format); err != nil {
+			return err
+		}
+		keys, err := parseMergeKeys(mergeKeys)
+		if err != nil {
+			return err
+		}
+		err = setTypeOverrides(typeOverrides)
+		if err != nil {
+			return err
+		}
+		config, err := loadConfig(cfgFile, cmd.Flags().Changed("config"))
+		if err != nil {
+			return err
+		}
+		rules, err := ignoreRules(config, ignoreFile)
+		if err != nil {
+			return err
+		}
+		originMapping, err := mapping(config, mappingFile)
+		if err != nil {
+			return err
+		}
+		metadata, err := optionMetadata(metadataDir, config.MetadataDir)
+		if err != nil {
+			return err
+		}
+		originMetadata, err := optionMetadata(originMetadataDir, config.OriginMetadataDir)
+		if err != nil {
+			return err
+		}
+		err = setRedaction(config, secretPatterns, showSecrets, metadata, originMetadata)
+		if err != nil {
+			return err
+		}
+		severities, err := severityRules(config, severityFile)
+		if err != nil {
+			return err
+		}
+		if minSeverity != "" {
+			if err := godiff.ValidateSeverity(minSeverity); err != nil {
+				return err
+			}
+		}
+		if jobs < 1 {
+			return fmt.Errorf("Invalid number of jobs: %d, expected at least 1", jobs)
+		}
+		if base != "" {
+			if _, err := os.Stat(base); err != nil {
+				return fmt.Errorf("Failed to open base directory: '%s'. %s", base, err)
+			}
+		}
+		if format != godiff.TextFormat {
+			// Keep stdout for the report only
+			godiff.SetLogOutput(os.Stderr)
+		}
 		goDiff := &godiff.GoDiffDataStruct{
-			Origin:      origin,
-			Destination: destination,
+			Origin:         origin,
+			Destination:    destination,
+			PatchFormat:    patchFormat,
+			MergeKeys:      keys,
+			Format:         format,
+			OutputDir:      output,
+			CombinedPatch:  combinedPatch,
+			IgnoreRules:    rules,
+			Mapping:        originMapping,
+			Metadata:       metadata,
+			OriginMetadata: originMetadata,
+			Base:           base,
+			Jobs:           jobs,
+			SeverityRules:  severities,
+			MinSeverity:    minSeverity,
 		}
-		err := goDiff.ProcessDirectories(reverse)
+		goDiff.Report.ShowIgnored = showIgnored
+		err = goDiff.ProcessDirectories(reverse)
 		if err != nil {
-			panic(err)
+			return err
 		}
+		if goDiff.Report.Fails(failOn) {
+			return errDifferences
+		}
+		return nil
 	},
 }
 
+func parseMergeKeys(values []string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid merge key: %s, expected <list path or name>=<field>", value)
+		}
+		keys[parts[0]] = parts[1]
+	}
+	return keys, nil
+}
+
+func setTypeOverrides(values []string) error {
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid type override: %s, expected <glob>=<type>", value)
+		}
+		if err := godiff.SetTypeOverride(parts[0], parts[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func init() {
 	compareCmd.Flags().StringVarP(&origin, "origin", "o", "", "Origin file or directory.")
 	compareCmd.Flags().StringVarP(&destination, "destination", "d", "", "Destination file or directory")
-	compareCmd.Flags().StringVar(&output, "output", "output.txt", "Output file (default is $PWD/output.txt)")
+	compareCmd.Flags().StringVar(&base, "base", "", "Base directory of a three-way comparison, such as upstream defaults or a pristine deployment.")
+	compareCmd.Flags().StringVar(&output, "output", "os-diff-output", "Output directory for the diff, patch and report files, mirroring the compared tree.")
+	compareCmd.Flags().BoolVar(&combinedPatch, "combined-patch", false, "Write a single os-diff.patch file in the output directory instead of a diff per file.")
 	compareCmd.Flags().BoolVar(&reverse, "reverse", false, "Search difference in both directories: origin and destination.")
+	compareCmd.Flags().MarkDeprecated("reverse", "paths only in the destination are always reported")
+	compareCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of files compared at once.")
+	compareCmd.Flags().StringVar(&format, "format", godiff.TextFormat, "Report format: text, json, yaml, html or junit.")
+	compareCmd.Flags().StringVar(&patchFormat, "patch", "", "Also write JSON and YAML differences as a patch: json-patch or merge-patch.")
+	compareCmd.Flags().StringSliceVar(&mergeKeys, "merge-key", nil, "Match JSON/YAML list items by a field, e.g. containers=name or spec.template.spec.volumes=name.")
+	compareCmd.Flags().StringSliceVar(&typeOverrides, "type-override", nil, "Force the type of matching files, e.g. *.conf=ini. Types: ini, json, yaml, text.")
+	compareCmd.Flags().StringVar(&failOn, "fail-on", godiff.FailOnAny, "Exit with 1 on: any difference, missing files or directories only, never, or differences of at least a severity: info, warning or critical.")
+	compareCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "YAML file of rules ignoring expected differences.")
+	compareCmd.Flags().BoolVar(&showIgnored, "show-ignored", false, "List the ignored differences in the report.")
+	compareCmd.Flags().StringVar(&mappingFile, "mapping-file", "", "YAML file translating the origin into the destination layout before comparing.")
+	compareCmd.Flags().StringSliceVar(&secretPatterns, "secret-pattern", nil, "Regular expression of secret option names to mask, added to the defaults.")
+	compareCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not mask secret values in the differences and the log.")
+	compareCmd.Flags().StringVar(&severityFile, "severity-file", "", "YAML file of rules ranking the differences, before the built-in catalog.")
+	compareCmd.Flags().StringVar(&minSeverity, "min-severity", "", "Leave the differences below this severity out of the report: info, warning or critical.")
+	compareCmd.Flags().StringVar(&metadataDir, "metadata-dir", "", "Directory of oslo-config-generator YAML or JSON files: <service>/<file>.yaml or <file>.yaml.")
+	compareCmd.Flags().StringVar(&originMetadataDir, "origin-metadata-dir", "", "Directory of the oslo-config-generator files of the origin release, to tell the removed options.")
 	rootCmd.AddCommand(compareCmd)
 }
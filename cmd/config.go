@@ -0,0 +1,147 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os-diff/pkg/godiff"
+	"path/filepath"
+
+	"github.com/go-yaml/yaml"
+)
+
+const defaultConfigFile = ".os-diff.yaml"
+
+// projectConfig holds the settings of a project config file. Relative
+// paths are relative to the config file.
+type projectConfig struct {
+	IgnoreFile  string              `yaml:"ignore_file,omitempty"`
+	Ignore      []godiff.IgnoreRule `yaml:"ignore,omitempty"`
+	MappingFile string              `yaml:"mapping_file,omitempty"`
+	// Regular expressions of secret option names, added to the defaults
+	SecretPatterns []string `yaml:"secret_patterns,omitempty"`
+	// Rules ranking the differences before the built-in catalog
+	SeverityFile string                `yaml:"severity_file,omitempty"`
+	Severity     []godiff.SeverityRule `yaml:"severity,omitempty"`
+	// Directories of oslo-config-generator option definitions, for the
+	// destination and the origin releases
+	MetadataDir       string `yaml:"metadata_dir,omitempty"`
+	OriginMetadataDir string `yaml:"origin_metadata_dir,omitempty"`
+}
+
+func loadConfig(path string, explicit bool) (*projectConfig, error) {
+	// The default config file is optional, an explicit one is not
+	config := &projectConfig{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open config file: %s. %s", path, err)
+	}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", path, err)
+	}
+	if err := godiff.CompileIgnoreRules(config.Ignore); err != nil {
+		return nil, fmt.Errorf("Invalid ignore rule in %s: %s", path, err)
+	}
+	if err := godiff.CheckSeverityRules(config.Severity); err != nil {
+		return nil, fmt.Errorf("Invalid severity rule in %s: %s", path, err)
+	}
+	for _, file := range []*string{&config.IgnoreFile, &config.MappingFile, &config.SeverityFile, &config.MetadataDir, &config.OriginMetadataDir} {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(filepath.Dir(path), *file)
+		}
+	}
+	return config, nil
+}
+
+func ignoreRules(config *projectConfig, ignoreFile string) ([]godiff.IgnoreRule, error) {
+	// Rules of the config file come first, then those of the ignore files
+	rules := append([]godiff.IgnoreRule{}, config.Ignore...)
+	for _, path := range []string{config.IgnoreFile, ignoreFile} {
+		if path == "" {
+			continue
+		}
+		fileRules, err := godiff.LoadIgnoreRules(path)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	return rules, nil
+}
+
+func mapping(config *projectConfig, mappingFile string) (*godiff.Mapping, error) {
+	// The mapping file given on the command line replaces the config one
+	if mappingFile == "" {
+		mappingFile = config.MappingFile
+	}
+	if mappingFile == "" {
+		return nil, nil
+	}
+	return godiff.LoadMapping(mappingFile)
+}
+
+func optionMetadata(metadataDir string, configDir string) (*godiff.Metadata, error) {
+	// The directory given on the command line replaces the config one
+	if metadataDir == "" {
+		metadataDir = configDir
+	}
+	if metadataDir == "" {
+		return nil, nil
+	}
+	return godiff.LoadMetadataDir(metadataDir)
+}
+
+func setRedaction(config *projectConfig, patterns []string, showSecrets bool, metadata ...*godiff.Metadata) error {
+	if showSecrets {
+		godiff.SetRedactor(nil)
+		return nil
+	}
+	patterns = append(append([]string{}, config.SecretPatterns...), patterns...)
+	for _, m := range metadata {
+		// Options declared secret are masked whatever their name
+		if m != nil {
+			patterns = append(patterns, m.SecretPatterns()...)
+		}
+	}
+	redactor, err := godiff.NewRedactor(patterns)
+	if err != nil {
+		return err
+	}
+	godiff.SetRedactor(redactor)
+	return nil
+}
+
+func severityRules(config *projectConfig, severityFile string) ([]godiff.SeverityRule, error) {
+	// Rules of the command line file come first, then those of the config
+	var rules []godiff.SeverityRule
+	for _, path := range []string{severityFile, config.SeverityFile} {
+		if path == "" {
+			continue
+		}
+		fileRules, err := godiff.LoadSeverityRules(path)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	return append(rules, config.Severity...), nil
+}
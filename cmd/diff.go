@@ -25,27 +25,60 @@ import (
 // diffCmd represents the diff command
 var source string
 var dest string
+var diffPatchFormat string
+var diffMergeKeys []string
+var diffTypeOverrides []string
+var diffSecretPatterns []string
+var diffShowSecrets bool
 
 var diffCmd = &cobra.Command{
 	Use:   "diff",
 	Short: "Print diff for two specific files",
 	Long: `Print diff for files provided via the command line: For example:
 os-diff diff --origin=tests/podman/keystone.conf --destination=tests/ocp/keystone.conf`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := parseMergeKeys(diffMergeKeys)
+		if err != nil {
+			return err
+		}
+		err = setTypeOverrides(diffTypeOverrides)
+		if err != nil {
+			return err
+		}
+		config, err := loadConfig(cfgFile, cmd.Flags().Changed("config"))
+		if err != nil {
+			return err
+		}
+		err = setRedaction(config, diffSecretPatterns, diffShowSecrets)
+		if err != nil {
+			return err
+		}
 		goDiff := &godiff.CompareFileNames{
 			Origin:      source,
 			Destination: dest,
+			PatchFormat: diffPatchFormat,
+			MergeKeys:   keys,
 		}
 
-		err := goDiff.DiffFiles()
+		err = goDiff.DiffFiles()
 		if err != nil {
-			panic(err)
+			return err
+		}
+		printSecretPaths(goDiff)
+		if goDiff.HasDifferences() {
+			return errDifferences
 		}
+		return nil
 	},
 }
 
 func init() {
 	diffCmd.Flags().StringVarP(&source, "origin", "o", "", "Source file.")
 	diffCmd.Flags().StringVarP(&dest, "destination", "d", "", "Destination file.")
+	diffCmd.Flags().StringVar(&diffPatchFormat, "patch", "", "Print JSON and YAML differences as a patch: json-patch or merge-patch.")
+	diffCmd.Flags().StringSliceVar(&diffMergeKeys, "merge-key", nil, "Match JSON/YAML list items by a field, e.g. containers=name.")
+	diffCmd.Flags().StringSliceVar(&diffTypeOverrides, "type-override", nil, "Force the type of matching files, e.g. *.conf=ini. Types: ini, json, yaml, text.")
+	diffCmd.Flags().StringSliceVar(&diffSecretPatterns, "secret-pattern", nil, "Regular expression of secret option names to mask, added to the defaults.")
+	diffCmd.Flags().BoolVar(&diffShowSecrets, "show-secrets", false, "Do not mask secret values in the differences.")
 	rootCmd.AddCommand(diffCmd)
 }
@@ -17,36 +17,148 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os-diff/pkg/godiff"
 
 	"github.com/spf13/cobra"
 )
 
+var genOrigin string
+var genDestination string
+var genFormat string
+var genOutput string
+var genService string
+var genFile string
+var genExcludes []string
+var genMetadataDir string
+var genConfigFormat string
+var genShowSecrets bool
+
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
 	Use:   "generate",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("generate called")
-	},
+	Short: "Generate files from the differences between two configurations",
+	Long: `Generate files from the differences found between an origin and a
+destination configuration. For example:
+  os-diff generate patch --origin=tests/podman/keystone.yaml --destination=tests/ocp/keystone.yaml`,
 }
 
-func init() {
-	rootCmd.AddCommand(generateCmd)
+var generatePatchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Generate a JSON Patch or a JSON Merge Patch between two JSON or YAML files",
+	Long: `Generate the patch turning the origin file into the destination file.
+The patch can be applied with kubectl patch or oc patch. For example:
+  os-diff generate patch -o origin.yaml -d destination.yaml --format=merge-patch
+  oc patch openstackcontrolplane openstack --type=merge --patch-file=patch.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := loadConfig(cfgFile, cmd.Flags().Changed("config"))
+		if err != nil {
+			return err
+		}
+		if err := setRedaction(config, nil, genShowSecrets); err != nil {
+			return err
+		}
+		// Keep stdout for the patch only
+		godiff.SetLogOutput(os.Stderr)
+		goDiff := &godiff.CompareFileNames{
+			Origin:      genOrigin,
+			Destination: genDestination,
+		}
+		patch, err := goDiff.GeneratePatch(genFormat)
+		if err != nil {
+			return err
+		}
+		printSecretPaths(goDiff)
+		if genOutput == "" {
+			fmt.Println(string(patch))
+			return nil
+		}
+		return ioutil.WriteFile(genOutput, append(patch, '\n'), 0644)
+	},
+}
 
-	// Here you will define your flags and configuration settings.
+func printSecretPaths(goDiff *godiff.CompareFileNames) {
+	for _, path := range goDiff.SecretPaths {
+		fmt.Fprintf(os.Stderr, "Secret left out of the patch: %s#%s, use --show-secrets to include it\n", goDiff.Destination, path)
+	}
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// generateCmd.PersistentFlags().String("foo", "", "A help for foo")
+var generateCustomConfigCmd = &cobra.Command{
+	Use:   "custom-config",
+	Short: "Generate the customServiceConfig carrying over the INI options missing in the destination",
+	Long: `Generate the customServiceConfig of a service in the OpenStackControlPlane,
+holding the INI options set in the origin and missing in the destination.
+Excluded options and secrets are listed as comments. For example:
+  os-diff generate custom-config --service keystone -o /tmp/collect_tripleo_configs -d /tmp/collect_crc_configs
+  os-diff generate custom-config --service keystone -o /tmp/collect_tripleo_configs -d /tmp/collect_crc_configs --format=patch --output=patch.yaml
+  oc patch openstackcontrolplane openstack --type=merge --patch-file=patch.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if genService == "" {
+			return fmt.Errorf("The service to generate the config of is required: --service")
+		}
+		config, err := loadConfig(cfgFile, cmd.Flags().Changed("config"))
+		if err != nil {
+			return err
+		}
+		rules, err := ignoreRules(config, "")
+		if err != nil {
+			return err
+		}
+		originMapping, err := mapping(config, "")
+		if err != nil {
+			return err
+		}
+		metadata, err := optionMetadata(genMetadataDir, config.MetadataDir)
+		if err != nil {
+			return err
+		}
+		// Secrets are never written out
+		if err := setRedaction(config, nil, false, metadata); err != nil {
+			return err
+		}
+		// Keep stdout for the config only
+		godiff.SetLogOutput(os.Stderr)
+		customConfig := &godiff.CustomConfig{
+			Service:     genService,
+			FileName:    genFile,
+			Excludes:    genExcludes,
+			IgnoreRules: rules,
+			Mapping:     originMapping,
+			Metadata:    metadata,
+		}
+		if err := customConfig.Collect(genOrigin, genDestination); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := customConfig.Write(&buf, genConfigFormat); err != nil {
+			return err
+		}
+		if genOutput == "" {
+			fmt.Print(buf.String())
+			return nil
+		}
+		return ioutil.WriteFile(genOutput, buf.Bytes(), 0644)
+	},
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// generateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+func init() {
+	generatePatchCmd.Flags().StringVarP(&genOrigin, "origin", "o", "", "Origin file.")
+	generatePatchCmd.Flags().StringVarP(&genDestination, "destination", "d", "", "Destination file.")
+	generatePatchCmd.Flags().StringVar(&genFormat, "format", godiff.JSONPatch, "Patch format: json-patch or merge-patch.")
+	generatePatchCmd.Flags().StringVar(&genOutput, "output", "", "Write the patch to this file instead of stdout.")
+	generatePatchCmd.Flags().BoolVar(&genShowSecrets, "show-secrets", false, "Do not mask secret values in the patch.")
+	generateCmd.AddCommand(generatePatchCmd)
+	generateCustomConfigCmd.Flags().StringVarP(&genOrigin, "origin", "o", "", "Origin file or directory.")
+	generateCustomConfigCmd.Flags().StringVarP(&genDestination, "destination", "d", "", "Destination file or directory.")
+	generateCustomConfigCmd.Flags().StringVar(&genService, "service", "", "Service to generate the customServiceConfig of, e.g. keystone.")
+	generateCustomConfigCmd.Flags().StringVar(&genFile, "file", "", "Name of the INI files to compare in the directories, <service>.conf by default.")
+	generateCustomConfigCmd.Flags().StringVar(&genConfigFormat, "format", godiff.SnippetFormat, "Output format: snippet, the customServiceConfig block, or patch, a merge patch of the OpenStackControlPlane.")
+	generateCustomConfigCmd.Flags().StringSliceVar(&genExcludes, "exclude", nil, "Options left out, globs on option or section.option names, added to the defaults.")
+	generateCustomConfigCmd.Flags().StringVar(&genMetadataDir, "metadata-dir", "", "Directory of oslo-config-generator YAML or JSON files, options set to their default are left out.")
+	generateCustomConfigCmd.Flags().StringVar(&genOutput, "output", "", "Write the config to this file instead of stdout.")
+	generateCmd.AddCommand(generateCustomConfigCmd)
+	rootCmd.AddCommand(generateCmd)
 }
@@ -34,7 +34,7 @@ var pullCmd = &cobra.Command{
 	Long: `This command pulls configuration files by services from Podman
 	environment or OCP. For example:
   os-diff pull --cloud_engine=ocp --inventory=$PWD/hosts --output-dir=/tmp`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		ansiblePlaybookConnectionOptions := &ansible.AnsiblePlaybookConnectionOptions{
 			Connection: "local",
@@ -57,10 +57,7 @@ var pullCmd = &cobra.Command{
 			Options:           ansiblePlaybookOptions,
 		}
 
-		err := playbook.Run()
-		if err != nil {
-			panic(err)
-		}
+		return playbook.Run()
 	},
 }
 
@@ -17,6 +17,8 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -31,15 +33,41 @@ or podman containers. For example:
 
 You can pull configuration from a Keystone container and compare
 to a new Keystone pod which has been migrated.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
+var cfgFile string
+
+// Exit codes, as diff(1) does
+const (
+	exitIdentical   = 0
+	exitDifferences = 1
+	exitError       = 2
+)
+
+// errDifferences is returned by commands which found differences, to exit
+// with exitDifferences without printing an error.
+var errDifferences = errors.New("Differences found")
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err != nil && err != errDifferences {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	switch err {
+	case nil:
+		return exitIdentical
+	case errDifferences:
+		return exitDifferences
 	}
+	return exitError
 }
 
 func init() {
@@ -47,7 +75,7 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.os-diff.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultConfigFile, "Project config file.")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
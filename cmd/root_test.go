@@ -0,0 +1,79 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.conf":                 "[DEFAULT]\ndebug = true\n",
+		"b.conf":                 "[DEFAULT]\ndebug = false\n",
+		"same/keystone.conf":     "[DEFAULT]\ndebug = true\n",
+		"origin/keystone.conf":   "[DEFAULT]\ndebug = true\n",
+		"origin/nova.conf":       "[DEFAULT]\ndebug = true\n",
+		"modified/keystone.conf": "[DEFAULT]\ndebug = false\n",
+		"modified/nova.conf":     "[DEFAULT]\ndebug = true\n",
+		"missing/keystone.conf":  "[DEFAULT]\ndebug = true\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+	compare := func(destination string, failOn string, extra ...string) []string {
+		args := []string{"compare", "-o", path("origin"), "-d", path(destination), "--output", t.TempDir(),
+			"--fail-on", failOn, "--format", "json", "--jobs", "2"}
+		return append(args, extra...)
+	}
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "diff identical", args: []string{"diff", "-o", path("a.conf"), "-d", path("same/keystone.conf")}, want: exitIdentical},
+		{name: "diff different", args: []string{"diff", "-o", path("a.conf"), "-d", path("b.conf")}, want: exitDifferences},
+		{name: "diff missing file", args: []string{"diff", "-o", path("none.conf"), "-d", path("b.conf")}, want: exitError},
+		{name: "unknown flag", args: []string{"diff", "--unknown"}, want: exitError},
+		{name: "compare identical", args: compare("origin", "any"), want: exitIdentical},
+		{name: "compare modified", args: compare("modified", "any"), want: exitDifferences},
+		{name: "fail on never", args: compare("modified", "never"), want: exitIdentical},
+		{name: "fail on missing", args: compare("missing", "missing"), want: exitDifferences},
+		{name: "modified without missing", args: compare("modified", "missing"), want: exitIdentical},
+		{name: "invalid fail-on", args: compare("origin", "sometimes"), want: exitError},
+		{name: "invalid format", args: compare("origin", "any", "--format", "xml"), want: exitError},
+		{name: "invalid jobs", args: compare("origin", "any", "--jobs", "0"), want: exitError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rootCmd.SetArgs(test.args)
+			if got := exitCode(rootCmd.Execute()); got != test.want {
+				t.Errorf("os-diff %v exits with %d, want %d", test.args, got, test.want)
+			}
+		})
+	}
+}
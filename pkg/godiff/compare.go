@@ -17,17 +17,15 @@
 package godiff
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
 
 	"github.com/go-ini/ini"
-	"github.com/go-yaml/yaml"
+	"github.com/sirupsen/logrus"
 )
 
 var Reset = "\033[0m"
@@ -38,6 +36,73 @@ type CompareFileNames struct {
 	Origin      string
 	Destination string
 	DiffReport  []string
+	Changes     []Change
+	// Type detected for the files and the detection confidence, out of 100
+	FileType       string
+	TypeConfidence int
+	// Write the structured difference as a patch too: json-patch or merge-patch
+	PatchFormat string
+	// JSON pointers of the differing secrets left out of the last patch
+	SecretPaths []string
+	// Fields identifying list items by list path or list name, added to DefaultMergeKeys
+	MergeKeys map[string]string
+	// Path, without extension, of the diff and patch files written by
+	// CompareFiles. They are written next to the origin file when empty.
+	OutputPath string
+	// Rules suppressing expected differences, and the service of the files
+	// they are matched with. Suppressed changes are kept in Ignored.
+	IgnoreRules []IgnoreRule
+	Service     string
+	// Translation of the origin into the destination layout, applied
+	// before comparing
+	Mapping *Mapping
+	// Option definitions of the INI files: types, defaults and known names
+	Metadata *Metadata
+	// Option definitions of the origin release, telling the options
+	// removed since
+	OriginMetadata *Metadata
+	// Rules ranking the changes before the built-in catalog, and the
+	// severity below which changes are left out and counted in Filtered
+	SeverityRules []SeverityRule
+	MinSeverity   string
+	Filtered      int
+	Ignored       []Change
+	// Differences leaving the meaning of the files unchanged, such as
+	// options spelled differently
+	Notes []string
+	// Base file of a three-way comparison, such as the upstream defaults
+	// or a pristine deployment. The changes of the origin from the base
+	// found as is in the destination are kept in CarriedOver.
+	Base        string
+	CarriedOver []Change
+	baseOrigin  []Change
+	baseDest    []Change
+	// Translate the destination too, when it is in the origin layout
+	mapDestination bool
+	// Leave the diff out of the written files, for a combined patch
+	skipDiffFile bool
+	// Leave the comparison out of the log, for the base of a three-way
+	// comparison
+	quiet       bool
+	orgContent  []byte
+	destContent []byte
+	orgData     interface{}
+	destData    interface{}
+}
+
+// quietLog drops the log of the comparisons with the base file.
+var quietLog = &logrus.Logger{
+	Out:       ioutil.Discard,
+	Formatter: new(logrus.TextFormatter),
+	Hooks:     make(logrus.LevelHooks),
+	Level:     logrus.PanicLevel,
+}
+
+func (f *CompareFileNames) logger() *logrus.Logger {
+	if f.quiet {
+		return quietLog
+	}
+	return log
 }
 
 func writeReport(content []string, reportPath string) error {
@@ -57,228 +122,368 @@ func writeReport(content []string, reportPath string) error {
 }
 
 func (f *CompareFileNames) Compare(origin []byte, destination []byte) error {
-	log.Info("Start basic line by line comparison")
-	// Split both files into lines
-	file1 := strings.Split(string(origin), "\n")
-	file2 := strings.Split(string(destination), "\n")
-
-	found := false
-	diffFound := false
-	msg := string("")
-	for i, line1 := range file1 {
-		found = false
-		// Skip comments
-		if !strings.HasPrefix(line1, "#") && len(line1) > 0 {
-			for _, line2 := range file2 {
-				if !strings.HasPrefix(line2, "#") && len(line2) > 0 {
-					if line1 == line2 {
-						found = true
-						break
-					}
-				}
-			}
-			if !found {
-				log.Warn("Line: ", line1, " not found in: ", f.Destination, " line: ", i)
-				msg = fmt.Sprintf("@@ line: %d\n", i)
-				if !stringInSlice(msg, f.DiffReport) {
-					f.DiffReport = append(f.DiffReport, msg)
-				}
-				msg = fmt.Sprintf("+%s\n", line1)
-				f.DiffReport = append(f.DiffReport, msg)
-				diffFound = true
-			}
+	f.logger().Info("Start line by line comparison")
+	diff := unifiedDiff(splitLines(origin), splitLines(destination), f.Origin, f.Destination, DefaultContextLines)
+	if diff != nil {
+		if r := currentRedactor(); r != nil {
+			diff = r.redactLines(diff, f.Origin)
 		}
-	}
-	//var lineNb []string
-	var index int
-	for i, line2 := range file2 {
-		found = false
-		// Skip comments
-		if !strings.HasPrefix(line2, "#") && len(line2) > 0 {
-			for _, line1 := range file1 {
-				if !strings.HasPrefix(line1, "#") && len(line1) > 0 {
-					if line1 == line2 {
-						found = true
-						break
-					}
-				}
-			}
-			if !found {
-				log.Warn("Line: ", line2, " not found in: ", f.Origin, " line: ", i)
-				msg = fmt.Sprintf("@@ line: %d\n", i)
-				if !stringInSlice(msg, f.DiffReport) {
-					f.DiffReport = append(f.DiffReport, msg)
-					msg = fmt.Sprintf("-%s\n", line2)
-					f.DiffReport = append(f.DiffReport, msg)
-				} else {
-					index = sliceIndex(msg, f.DiffReport)
-					//lineNb = strings.Split(f.DiffReport[index], " ")
-					msg = fmt.Sprintf("-%s\n", line2)
-					f.DiffReport = append(f.DiffReport[:index+2], msg)
-				}
-				diffFound = true
-			}
-		}
-	}
-	if diffFound {
-		log.Warn("File: ", f.Origin, " has difference with: ", f.Destination)
-		msg := fmt.Sprintf("Source file path: %s, difference with: %s\n", f.Origin, f.Destination)
-		f.DiffReport = append([]string{msg}, f.DiffReport...)
+		f.logger().Warn("File: ", f.Origin, " has difference with: ", f.Destination)
+		f.DiffReport = append(f.DiffReport, diff...)
 	}
 	return nil
 }
 
 func (f *CompareFileNames) CompareJsonFiles(origin []byte, dest []byte) error {
-	// Unmarshal the JSON files into interface{}
-	var originData, destData interface{}
-	err := json.Unmarshal(origin, &originData)
+	orgData, err := parseJson(origin)
 	if err != nil {
-		return fmt.Errorf("Error unmarshalling %s, error: %s", origin, err)
+		return fmt.Errorf("Error unmarshalling %s, error: %s", f.Origin, err)
 	}
-	err = json.Unmarshal(dest, &destData)
+	destData, err := parseJson(dest)
 	if err != nil {
-		return fmt.Errorf("Error unmarshalling %s, error: %s", dest, err)
+		return fmt.Errorf("Error unmarshalling %s, error: %s", f.Destination, err)
 	}
-	compareJSON(originData, destData, "")
+	orgData, destData = f.mapTrees(orgData, destData)
+	f.orgData, f.destData = orgData, destData
+	f.AddChanges(f.treeComparer().compare(orgData, destData, ""))
 	return nil
 }
 
 func (f *CompareFileNames) CompareYamlFiles(origin []byte, dest []byte) error {
-	// Unmarshal the YAML files into maps
-	var map1, map2 map[interface{}]interface{}
-	err := yaml.Unmarshal(origin, &map1)
+	// Streams of several documents are compared line by line
+	for _, content := range [][]byte{origin, dest} {
+		if count, err := yamlDocuments(content); err == nil && count > 1 {
+			f.logger().Info("Several YAML documents in: ", f.Origin, " or: ", f.Destination)
+			return f.Compare(origin, dest)
+		}
+	}
+	orgData, err := parseYaml(origin)
 	if err != nil {
-		return fmt.Errorf("Error unmarshalling %s, error: %s", origin, err)
+		return fmt.Errorf("Error unmarshalling %s, error: %s", f.Origin, err)
 	}
-	err = yaml.Unmarshal(dest, &map2)
+	destData, err := parseYaml(dest)
 	if err != nil {
-		return fmt.Errorf("Error unmarshalling %s, error: %s", dest, err)
-	}
-	// Compare the maps and print the differences
-	msg := string("")
-	if !reflect.DeepEqual(map1, map2) {
-		//printMapDiff(map1, map2)
-		for key, val1 := range map1 {
-			val2, isEqual := map2[key]
-			if !isEqual {
-				msg = fmt.Sprintf("+%v: %v\n", key, val1)
-				f.DiffReport = append(f.DiffReport, msg)
-			} else if !reflect.DeepEqual(val1, val2) {
-				// data1, _ := yaml.Marshal(&val1)
-				// data2, _ := yaml.Marshal(&val2)
-				// msg = fmt.Sprintf("+%v: %s\n-%v:%s\n", key, data1, key, data2)
-				data, _ := yaml.Marshal(&map1)
-				ioutil.WriteFile("/tmp/test.yaml", data, 0644)
-				msg = fmt.Sprintf("+%v: %s\n-%v:%s\n", key, val1, key, val2)
-				f.DiffReport = append(f.DiffReport, msg)
+		return fmt.Errorf("Error unmarshalling %s, error: %s", f.Destination, err)
+	}
+	orgData, destData = f.mapTrees(orgData, destData)
+	f.orgData, f.destData = orgData, destData
+	f.AddChanges(f.treeComparer().compare(orgData, destData, ""))
+	return nil
+}
+
+func (f *CompareFileNames) mapTrees(orgData interface{}, destData interface{}) (interface{}, interface{}) {
+	if f.Mapping == nil {
+		return orgData, destData
+	}
+	orgData = f.Mapping.applyTree(orgData, f.Service, f.Origin)
+	if f.mapDestination {
+		destData = f.Mapping.applyTree(destData, f.Service, f.Destination)
+	}
+	return orgData, destData
+}
+
+func (f *CompareFileNames) treeComparer() *treeComparer {
+	mergeKeys := make(map[string]string, len(DefaultMergeKeys)+len(f.MergeKeys))
+	for path, key := range DefaultMergeKeys {
+		mergeKeys[path] = key
+	}
+	for path, key := range f.MergeKeys {
+		mergeKeys[path] = key
+	}
+	return &treeComparer{mergeKeys: mergeKeys}
+}
+
+// AddChanges records the structured changes found by a comparator and
+// appends their text form to the report, after the three-way
+// classification, the ignore rules, the severity filter and the redaction.
+func (f *CompareFileNames) AddChanges(changes []Change) {
+	if f.Base != "" {
+		changes = f.classify(changes)
+	}
+	if len(f.IgnoreRules) > 0 {
+		var kept []Change
+		for _, change := range changes {
+			if reason, ok := ignoredChange(f.IgnoreRules, f.Service, f.Origin, change); ok {
+				f.logger().Info("Difference ignored (", reason, ") at: ", change.Path, " in: ", f.Destination)
+				change.IgnoreReason = reason
+				f.Ignored = append(f.Ignored, change)
+				continue
 			}
+			kept = append(kept, change)
 		}
-		// Loop through the keys in map2 and check for any missing keys
-		for key := range map2 {
-			_, isEqual := map1[key]
-			if !isEqual {
-				msg = fmt.Sprintf("-%v: %v\n", key, map2[key])
-				f.DiffReport = append(f.DiffReport, msg)
-			}
+		changes = kept
+	}
+	var kept []Change
+	for _, change := range changes {
+		change.Severity = changeSeverity(f.SeverityRules, f.Service, f.Origin, change)
+		if f.MinSeverity != "" && !severityAtLeast(change.Severity, f.MinSeverity) {
+			f.Filtered++
+			continue
 		}
+		kept = append(kept, change)
 	}
-	return nil
+	changes = kept
+	if r := currentRedactor(); r != nil {
+		changes = r.redactChanges(changes, f.Origin)
+		f.Ignored = r.redactChanges(f.Ignored, f.Origin)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		f.logger().Warn("Difference detected (", change.Kind, ") at: ", change.Path, " in: ", f.Destination)
+	}
+	f.logger().Warn("File: ", f.Origin, " has difference with: ", f.Destination)
+	msg := fmt.Sprintf("Source file path: %s, difference with: %s\n", f.Origin, f.Destination)
+	f.Changes = append(f.Changes, changes...)
+	f.DiffReport = append(f.DiffReport, msg)
+	f.DiffReport = append(f.DiffReport, formatChanges(changes)...)
+}
+
+func iniChange(section string, key string, kind string, old interface{}, new interface{}) Change {
+	path := section
+	if key != "" {
+		path = section + "." + canonicalOption(key)
+	}
+	return Change{Path: path, Section: section, Key: key, Kind: kind, Old: old, New: new}
 }
 
 func (f *CompareFileNames) CompareIniFiles(origin string, dest string) error {
-	// Load the INI files
-	cfg1, err := ini.Load(origin)
+	return f.compareIni(origin, dest, origin, dest)
+}
+
+func (f *CompareFileNames) compareIni(orgSource interface{}, destSource interface{}, origin string, dest string) error {
+	// Load the INI files, sources are file paths or contents
+	cfg1, err := ini.Load(orgSource)
 	if err != nil {
-		log.Error("Error while loading file: ", origin, err)
+		f.logger().Error("Error while loading file: ", origin, err)
 		return fmt.Errorf("Error while loading file %s: %s", origin, err)
 	}
-	cfg2, err := ini.Load(dest)
+	cfg2, err := ini.Load(destSource)
 	if err != nil {
-		log.Error("Error while loading file: ", dest, err)
-		return fmt.Errorf("Erro while loading file %s: %s", dest, err)
+		f.logger().Error("Error while loading file: ", dest, err)
+		return fmt.Errorf("Error while loading file %s: %s", dest, err)
+	}
+	if f.Mapping != nil {
+		f.Mapping.applyIni(cfg1, f.Service, origin)
+		if f.mapDestination {
+			f.Mapping.applyIni(cfg2, f.Service, dest)
+		}
 	}
 
-	diffFound := false
-	sectionFound := false
-	msg := string("")
-	// Compare the sections and keys in each file
-	for _, sec1 := range cfg1.Sections() {
-		sectionFound = false
-		sec2, err := cfg2.GetSection(sec1.Name())
-		if err != nil {
-			msg := fmt.Sprintf("-[%s]\n", sec1.Name())
-			if !stringInSlice(msg, f.DiffReport) {
-				diffFound = true
-				log.Warn("Difference detected. Section: ", sec1.Name(), " not found in:", dest)
-				f.DiffReport = append(f.DiffReport, msg)
-				break
+	// Sections and options are matched by their canonical names
+	sections1, index1 := indexIni(cfg1)
+	sections2, index2 := indexIni(cfg2)
+	options := f.Metadata.options(f.Service, origin)
+	originOptions := f.OriginMetadata.options(f.Service, origin)
+	if !f.quiet {
+		f.Notes = append(f.Notes, options.optionNotes(sections1, origin, "origin", originOptions)...)
+		f.Notes = append(f.Notes, options.optionNotes(sections2, dest, "destination", originOptions)...)
+	}
+	var changes []Change
+	// Options missing on one side with their default value on the other
+	keyChange := func(section string, key *ini.Key, kind string) {
+		c := iniChange(section, key.Name(), kind, nil, nil)
+		if kind == ChangeRemoved {
+			c.Old = key.Value()
+		} else {
+			c.New = key.Value()
+		}
+		if options.isDefault(section, key.Name(), key.Value()) {
+			f.logger().Info("Option: ", section, ".", key.Name(), " ", kind, " with its default value in: ", dest)
+			c.IgnoreReason = DefaultValueReason
+			f.Ignored = append(f.Ignored, c)
+			return
+		}
+		c.addNote(options.status(section, key.Name(), originOptions))
+		changes = append(changes, c)
+	}
+	compareKeys := func(section1 string, key1 *ini.Key, section2 string, key2 *ini.Key) {
+		var change *Change
+		if note, isURL := urlDifferences(key1.Value(), key2.Value()); isURL {
+			if note != "" {
+				f.logger().Warn("Difference detected: URLs are not equivalent: ", note,
+					" Section: ", section1, " Key ", key1.Name(), " ", dest)
+				c := iniChange(section1, key1.Name(), ChangeModified, key1.Value(), key2.Value())
+				c.Note = note
+				change = &c
 			}
+		} else if !options.valuesEqual(section1, key1.Name(), key1.Value(), key2.Value()) {
+			f.logger().Warn("Difference detected: Values are not equal: ",
+				redactOption(key1.Name(), key1.Value()), " and ", redactOption(key2.Name(), key2.Value()),
+				"Section: ", section1, " Key ", key1.Name(), dest)
+			c := iniChange(section1, key1.Name(), ChangeModified, key1.Value(), key2.Value())
+			change = &c
 		}
-		for _, key1 := range sec1.Keys() {
-			key2, err := sec2.GetKey(key1.Name())
-			if err != nil {
-				if !sectionFound {
-					sectionFound = true
-					msg = fmt.Sprintf("[%s]\n-%s=%s\n", sec1.Name(), key1.Name(), key1.Value())
+		if section1 != section2 || canonicalOption(key1.Name()) != canonicalOption(key2.Name()) {
+			// Former and current names of an option
+			f.logger().Warn("Option: ", section1, ".", key1.Name(), " is named: ", section2, ".", key2.Name(), " in: ", dest)
+			if change == nil {
+				c := iniChange(section1, key1.Name(), ChangeRenamed, key1.Value(), key2.Value())
+				change = &c
+			}
+			if _, current := options.current(section1, key1.Name()); current {
+				change.addNote(fmt.Sprintf("deprecated name %s.%s in destination", section2, key2.Name()))
+			} else {
+				change.addNote(fmt.Sprintf("deprecated name, renamed %s.%s in destination", section2, key2.Name()))
+			}
+		} else {
+			if key1.Name() != key2.Name() {
+				// The same option, only noted when its value is unchanged
+				f.logger().Info("Option: ", key1.Name(), " is spelled: ", key2.Name(), " in: ", dest)
+				note := fmt.Sprintf("spelled %s in origin and %s in destination", key1.Name(), key2.Name())
+				if change == nil {
+					f.Notes = append(f.Notes, fmt.Sprintf("option %s.%s spelled %s in destination", section1, key1.Name(), key2.Name()))
 				} else {
-					msg = fmt.Sprintf("-%s=%s\n", key1.Name(), key1.Value())
+					change.addNote(note)
 				}
-				if !stringInSlice(msg, f.DiffReport) {
-					diffFound = true
-					log.Warn("Difference detected. Section: ", sec1.Name(), " Key ", key1.Name(), " not found in:", dest)
-					f.DiffReport = append(f.DiffReport, msg)
+			}
+			if change != nil {
+				change.addNote(options.status(section1, key1.Name(), originOptions))
+			}
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	// Options renamed in the destination release are matched with their
+	// former names, on either side
+	renamed := options.keysByOption(sections2)
+	matched := make(map[*ini.Key]bool)
+	findRenamed := func(section string, key *ini.Key) bool {
+		opt, ok := options.lookup(section, key.Name())
+		if !ok {
+			return false
+		}
+		other, ok := renamed[opt]
+		if !ok || matched[other.key] {
+			return false
+		}
+		if sec1, ok := index1[other.section]; ok {
+			if _, ok := sec1.index[canonicalOption(other.key.Name())]; ok {
+				// Matched by its own name
+				return false
+			}
+		}
+		matched[other.key] = true
+		compareKeys(section, key, other.section, other.key)
+		return true
+	}
+	for _, sec1 := range sections1 {
+		sec2, ok := index2[sec1.name]
+		if !ok {
+			var removed []*ini.Key
+			for _, key1 := range sec1.keys {
+				if !findRenamed(sec1.name, key1) {
+					removed = append(removed, key1)
 				}
-			} else {
-				if key1.Value() != key2.Value() {
-					if !sectionFound {
-						sectionFound = true
-						msg = fmt.Sprintf(
-							"[%s]\n+%s=%s\n-%s=%s\n",
-							sec1.Name(),
-							key1.Name(),
-							key1.Value(),
-							key2.Name(),
-							key2.Value(),
-						)
-					} else {
-						msg = fmt.Sprintf("+%s=%s\n-%s=%s\n", key1.Name(), key1.Value(), key2.Name(), key2.Value())
-					}
-					if !stringInSlice(msg, f.DiffReport) {
-						diffFound = true
-						log.Warn("Difference detected: Values are not equal: ",
-							key1.Value(), " and ", key2.Value(),
-							"Section: ", sec1.Name(), " Key ", key1.Name(), dest)
-						f.DiffReport = append(f.DiffReport, msg)
-					}
+			}
+			if len(removed) == 0 && len(sec1.keys) > 0 {
+				// All the options moved to other sections
+				continue
+			}
+			f.logger().Warn("Difference detected. Section: ", sec1.name, " not found in:", dest)
+			changes = append(changes, iniChange(sec1.name, "", ChangeRemoved, nil, nil))
+			for _, key1 := range removed {
+				keyChange(sec1.name, key1, ChangeRemoved)
+			}
+			continue
+		}
+		if sec1.written != "" && sec2.written != "" && sec1.written != sec2.written {
+			f.logger().Info("Section: ", sec1.written, " is spelled: ", sec2.written, " in: ", dest)
+			f.Notes = append(f.Notes, fmt.Sprintf("section spelled [%s] in origin and [%s] in destination", sec1.written, sec2.written))
+		}
+		for _, key1 := range sec1.keys {
+			key2, ok := sec2.index[canonicalOption(key1.Name())]
+			if !ok {
+				if findRenamed(sec1.name, key1) {
+					continue
 				}
+				f.logger().Warn("Difference detected. Section: ", sec1.name, " Key ", key1.Name(), " not found in:", dest)
+				keyChange(sec1.name, key1, ChangeRemoved)
+				continue
 			}
+			compareKeys(sec1.name, key1, sec1.name, key2)
 		}
 		// Look for missing keys in Origin:
-		for _, key2 := range sec2.Keys() {
-			_, err := sec1.GetKey(key2.Name())
-			if err != nil {
-				if !sectionFound {
-					sectionFound = true
-					msg = fmt.Sprintf("[%s]\n-%s=%s\n", sec2.Name(), key2.Name(), key2.Value())
-				} else {
-					msg = fmt.Sprintf("-%s=%s\n", key2.Name(), key2.Value())
-				}
-				if !stringInSlice(msg, f.DiffReport) {
-					diffFound = true
-					log.Warn("Difference detected -- Section: ", sec2.Name(), " Key ", key2.Name(), " not found in:", dest)
-					f.DiffReport = append(f.DiffReport, msg)
+		for _, key2 := range sec2.keys {
+			if _, ok := sec1.index[canonicalOption(key2.Name())]; !ok && !matched[key2] {
+				f.logger().Warn("Difference detected -- Section: ", sec2.name, " Key ", key2.Name(), " not found in:", origin)
+				keyChange(sec2.name, key2, ChangeAdded)
+			}
+		}
+	}
+	// Look for missing sections in Origin:
+	for _, sec2 := range sections2 {
+		if _, ok := index1[sec2.name]; !ok {
+			var added []*ini.Key
+			for _, key2 := range sec2.keys {
+				if !matched[key2] {
+					added = append(added, key2)
 				}
 			}
+			if len(added) == 0 && len(sec2.keys) > 0 {
+				// All the options moved from other sections
+				continue
+			}
+			f.logger().Warn("Difference detected. Section: ", sec2.name, " not found in:", origin)
+			changes = append(changes, iniChange(sec2.name, "", ChangeAdded, nil, nil))
+			for _, key2 := range added {
+				keyChange(sec2.name, key2, ChangeAdded)
+			}
 		}
 	}
-	if diffFound {
-		log.Warn("File: ", origin, " has difference with: ", dest)
-		msg := fmt.Sprintf("Source file path: %s, difference with: %s\n", origin, dest)
-		f.DiffReport = append([]string{msg}, f.DiffReport...)
+	f.AddChanges(changes)
+	return nil
+}
+
+func (f *CompareFileNames) CheckOptions() error {
+	/*
+		Note the deprecated, removed and unknown options of identical INI
+		files, which are not compared.
+	*/
+	options := f.Metadata.options(f.Service, f.Origin)
+	if options == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(f.Origin)
+	if err != nil {
+		return errors.New("Failed to open file: '" + f.Origin + "'. " + err.Error())
+	}
+	if comparator, _ := comparatorFor(f.Origin, f.Destination, content, content); comparator.Name() != IniType {
+		return nil
 	}
+	cfg, err := ini.Load(content)
+	if err != nil {
+		return fmt.Errorf("Error while loading file %s: %s", f.Origin, err)
+	}
+	sections, _ := indexIni(cfg)
+	f.Notes = options.optionNotes(sections, f.Origin, "origin and destination", f.OriginMetadata.options(f.Service, f.Origin))
 	return nil
 }
 
+func (f *CompareFileNames) compareContents(orgContent []byte, destContent []byte) error {
+	// Detect type
+	comparator, confidence := comparatorFor(f.Origin, f.Destination, orgContent, destContent)
+	if r := currentRedactor(); r != nil && r.secretFile(f.Origin) {
+		// Compare secret files line by line, every line is masked
+		comparator, confidence = textComparator{}, 100
+	}
+	f.FileType, f.TypeConfidence = comparator.Name(), confidence
+	f.logger().Info("Files detected as ", comparator.Name(), " files (confidence: ", confidence,
+		"%), start to process contents")
+	err := comparator.Compare(f, orgContent, destContent)
+	// if error occur, try to make a basic diff
+	if err != nil && comparator.Name() != TextType {
+		f.logger().Warn(
+			"Error while processing files: ",
+			f.Origin, " and ",
+			f.Destination, " try to compare as a standard type...")
+		return f.Compare(orgContent, destContent)
+	}
+	return err
+}
+
 func (f *CompareFileNames) CompareFiles() ([]string, error) {
 	// Read the files
 	log.Info("Start to compare file contents for: ", f.Origin, " and: ", f.Destination)
@@ -292,43 +497,70 @@ func (f *CompareFileNames) CompareFiles() ([]string, error) {
 		log.Error("Failed to read file", f.Origin, "\n")
 		return nil, errors.New("Failed to open file: '" + f.Destination + "'. " + err.Error())
 	}
-	// Detect type
-	if isIni(orgContent) && isIni(destContent) {
-		log.Info("Files detected as Ini files, start to process contents")
-		err := f.CompareIniFiles(f.Origin, f.Destination)
-		// if error occur, try to make a basic diff
-		if err != nil {
-			log.Warn(
-				"Error while processing files: ",
-				f.Origin, " and ",
-				f.Destination, " try to compare as a standard type...")
-			f.Compare(orgContent, destContent)
+	f.orgContent, f.destContent = orgContent, destContent
+	if f.Base != "" {
+		if err := f.compareBase(orgContent, destContent); err != nil {
+			return nil, err
 		}
-	} else if isJson(orgContent) && isJson(destContent) {
-		log.Info("Files detected as JSON files, start to process contents")
-		f.CompareJsonFiles(orgContent, destContent)
-	} else if isYaml(orgContent) && isYaml(destContent) {
-		log.Info("Files detected as YAML files, start to process contents")
-		f.Compare(orgContent, destContent)
-	} else {
-		log.Info("No specific type detected, process to a standard line by line comparison...")
-		// Check for differences
-		f.Compare(orgContent, destContent)
-	}
-	filePath := f.Origin + ".diff"
-	if len(f.DiffReport) != 0 {
+	}
+	err = f.compareContents(orgContent, destContent)
+	if err != nil {
+		return nil, err
+	}
+	outputPath := f.OutputPath
+	if outputPath == "" {
+		outputPath = f.Origin
+	}
+	filePath := outputPath + ".diff"
+	if len(f.DiffReport) != 0 && !f.skipDiffFile {
 		err = writeReport(f.DiffReport, filePath)
 		if err != nil {
 			log.Error("Error while trying to create diff file in the file system: ", filePath)
-			fmt.Println(err)
+			return nil, err
+		}
+	}
+	if f.PatchFormat != "" && len(f.Changes) > 0 {
+		patch, err := f.Patch(f.PatchFormat)
+		if err != nil {
+			return nil, err
+		}
+		err = writeReport([]string{string(patch), "\n"}, outputPath+".patch.json")
+		if err != nil {
+			log.Error("Error while trying to create patch file in the file system: ", outputPath+".patch.json")
+			return nil, err
+		}
+		for _, path := range f.SecretPaths {
+			log.Warn("Secret left out of the patch: ", f.Destination+"#"+path)
 		}
 	}
 	return f.DiffReport, nil
 }
 
+func (f *CompareFileNames) unifiedPatch(orgName string, destName string) []string {
+	// Line by line diff of the compared contents, whatever their type
+	diff := unifiedDiff(splitLines(f.orgContent), splitLines(f.destContent), orgName, destName, DefaultContextLines)
+	if r := currentRedactor(); r != nil {
+		diff = r.redactLines(diff, f.Origin)
+	}
+	return diff
+}
+
+// HasDifferences tells whether the last comparison found a difference.
+func (f *CompareFileNames) HasDifferences() bool {
+	return len(f.DiffReport) > 0 || len(f.Changes) > 0
+}
+
 func (f *CompareFileNames) DiffFiles() error {
 	// Drop logging
 	log.SetOutput(ioutil.Discard)
+	if f.PatchFormat != "" {
+		patch, err := f.GeneratePatch(f.PatchFormat)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(patch))
+		return nil
+	}
 	// Read the files
 	orgContent, err := ioutil.ReadFile(f.Origin)
 	if err != nil {
@@ -340,16 +572,16 @@ func (f *CompareFileNames) DiffFiles() error {
 		log.Error("Failed to read file", f.Origin, "\n")
 		return errors.New("Failed to open file: '" + f.Destination + "'. " + err.Error())
 	}
-	if isIni(orgContent) && isIni(destContent) {
-		f.CompareIniFiles(f.Origin, f.Destination)
-	} else {
-		f.Compare(orgContent, destContent)
+	err = f.compareContents(orgContent, destContent)
+	if err != nil {
+		return err
 	}
 
 	var output []string
-	f.DiffReport = strings.Split(strings.Join(f.DiffReport, ""), "\n")
-	for _, line := range f.DiffReport {
-		if strings.HasPrefix(line, "+") {
+	for _, line := range strings.Split(strings.Join(f.DiffReport, ""), "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			output = append(output, fmt.Sprintf("%s\n", line))
+		} else if strings.HasPrefix(line, "+") {
 			output = append(output, fmt.Sprintf("%s%s%s\n", Green, line, Reset))
 		} else if strings.HasPrefix(line, "-") {
 			output = append(output, fmt.Sprintf("%s%s%s\n", Red, line, Reset))
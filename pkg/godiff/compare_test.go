@@ -0,0 +1,107 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestCompareIniSpelling(t *testing.T) {
+	tests := []struct {
+		name    string
+		org     string
+		dest    string
+		changes string
+		notes   int
+	}{
+		{
+			name:  "option spelling",
+			org:   "[DEFAULT]\nmax-pool-size = 5\n",
+			dest:  "[DEFAULT]\nmax_pool_size = 5\n",
+			notes: 1,
+		},
+		{
+			name:  "section spelling",
+			org:   "[default]\ndebug = true\n",
+			dest:  "[DEFAULT]\ndebug = true\n",
+			notes: 1,
+		},
+		{
+			name:    "spelling and value",
+			org:     "[DEFAULT]\nmax-pool-size = 5\n",
+			dest:    "[DEFAULT]\nmax_pool_size = 6\n",
+			changes: "changed DEFAULT.max_pool_size",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Origin: "org.conf", Destination: "dest.conf"}
+			if err := f.compareIni([]byte(test.org), []byte(test.dest), f.Origin, f.Destination); err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(f.Changes); got != test.changes {
+				t.Errorf("Changes = %q, want %q", got, test.changes)
+			}
+			if len(f.Notes) != test.notes {
+				t.Errorf("Notes = %q, want %d notes", f.Notes, test.notes)
+			}
+		})
+	}
+}
+
+func TestCompareJsonFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		org     string
+		dest    string
+		changes string
+		err     bool
+	}{
+		{name: "keys reordered", org: `{"a": 1, "b": 2}`, dest: `{"b": 2, "a": 1}`},
+		{name: "same number", org: `{"a": 1}`, dest: `{"a": 1.0}`},
+		{name: "nested value", org: `{"a": {"b": 1}}`, dest: `{"a": {"b": 2}}`, changes: "changed a.b"},
+		{name: "key renamed", org: `{"a": 1}`, dest: `{"b": 1}`, changes: "removed a; added b"},
+		{name: "type changed", org: `{"a": 1}`, dest: `{"a": "1"}`, changes: "type_changed a"},
+		{name: "dotted key", org: `{"a.b": 1}`, dest: `{"a.b": 2}`, changes: `changed ["a.b"]`},
+		{name: "null removed", org: `{"a": null}`, dest: `{}`, changes: "removed a"},
+		{name: "top-level list", org: `[1, 2]`, dest: `[1, 3]`, changes: "added [1]; removed [1]"},
+		{name: "invalid origin", org: `{"a": }`, dest: `{}`, err: true},
+		{name: "trailing data", org: `{}`, dest: `{} {}`, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Origin: "org.json", Destination: "dest.json"}
+			err := f.CompareJsonFiles([]byte(test.org), []byte(test.dest))
+			if (err != nil) != test.err {
+				t.Fatalf("CompareJsonFiles() error = %v, want error %v", err, test.err)
+			}
+			if got := describeChanges(f.Changes); got != test.changes {
+				t.Errorf("Changes = %q, want %q", got, test.changes)
+			}
+			if (len(f.DiffReport) > 0) != (test.changes != "") {
+				t.Errorf("DiffReport = %q, want a report with the changes only", f.DiffReport)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of a custom service config.
+const (
+	SnippetFormat = "snippet"
+	CRPatchFormat = "patch"
+)
+
+// DefaultCustomConfigExcludes are the options set by the operator for the
+// destination deployment, never carried over. Patterns are globs on the
+// option name or on section.option.
+var DefaultCustomConfigExcludes = []string{
+	"transport_url",
+	"*connection",
+	"my_ip",
+	"host",
+	"bind_host",
+	"bind_port",
+	"*_listen",
+	"*_listen_port",
+	"auth_url",
+	"www_authenticate_uri",
+	"memcache_servers",
+	"memcached_servers",
+	"log_dir",
+	"log_file",
+	"state_path",
+	"lock_path",
+}
+
+// CustomConfig gathers the INI options set in the origin and missing in the
+// destination, to carry them over with the customServiceConfig of a
+// service in the OpenStackControlPlane.
+type CustomConfig struct {
+	Service string
+	// Name of the INI files compared, <service>.conf when empty
+	FileName string
+	// Patterns of the options left out, added to DefaultCustomConfigExcludes
+	Excludes    []string
+	IgnoreRules []IgnoreRule
+	Mapping     *Mapping
+	Metadata    *Metadata
+	// Options carried over, and those left out: excluded, secrets and
+	// options set to another value in the destination
+	Options  []Change
+	Excluded []Change
+	Secrets  []Change
+	Changed  []Change
+	files    []string
+	seen     map[string]bool
+}
+
+func (c *CustomConfig) excluded(change Change) bool {
+	name := canonicalOption(strings.ToLower(change.Key))
+	path := canonicalSection(change.Section) + "." + name
+	for _, patterns := range [][]string{DefaultCustomConfigExcludes, c.Excludes} {
+		for _, pattern := range patterns {
+			pattern = canonicalOption(strings.ToLower(pattern))
+			if match, _ := filepath.Match(pattern, name); match {
+				return true
+			}
+			if match, _ := filepath.Match(pattern, strings.ToLower(path)); match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Collect compares the origin and destination files of the service, origin
+// and destination being files or directories laid out by the pull command.
+// A file missing from the destination is read as empty.
+func (c *CustomConfig) Collect(origin string, destination string) error {
+	for _, pattern := range c.Excludes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid exclude pattern: %s, %s", pattern, err)
+		}
+	}
+	info, err := os.Stat(origin)
+	if err != nil {
+		return errors.New("Failed to open file: '" + origin + "'. " + err.Error())
+	}
+	if !info.IsDir() {
+		return c.add(origin, destination)
+	}
+	fileName := c.FileName
+	if fileName == "" {
+		fileName = c.Service + ".conf"
+	}
+	if _, err := os.Stat(filepath.Join(origin, c.Service)); err == nil {
+		origin, destination = filepath.Join(origin, c.Service), filepath.Join(destination, c.Service)
+	}
+	err = filepath.Walk(origin, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Base(path) != fileName {
+			return nil
+		}
+		relPath, _ := filepath.Rel(origin, path)
+		return c.add(path, filepath.Join(destination, relPath))
+	})
+	if err != nil {
+		return err
+	}
+	if len(c.files) == 0 {
+		return fmt.Errorf("No %s file found in: %s", fileName, origin)
+	}
+	return nil
+}
+
+func (c *CustomConfig) add(origin string, destination string) error {
+	var destSource interface{} = destination
+	if _, err := os.Stat(destination); os.IsNotExist(err) {
+		log.Warn("File is missing: ", destination, ", carry over all the options of: ", origin)
+		destSource = []byte{}
+	}
+	compare := CompareFileNames{
+		Origin:      origin,
+		Destination: destination,
+		IgnoreRules: c.IgnoreRules,
+		Service:     c.Service,
+		Mapping:     c.Mapping,
+		Metadata:    c.Metadata,
+	}
+	if err := compare.compareIni(origin, destSource, origin, destination); err != nil {
+		return err
+	}
+	c.files = append(c.files, origin)
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+	}
+	r := currentRedactor()
+	for _, change := range compare.Changes {
+		// The first file setting an option wins
+		if change.Key == "" || c.seen[change.Path] {
+			continue
+		}
+		switch change.Kind {
+		case ChangeRemoved:
+			c.seen[change.Path] = true
+			value := fmt.Sprint(change.Old)
+			switch {
+			case c.excluded(change):
+				c.Excluded = append(c.Excluded, change)
+			case r != nil && r.secretOption(change.Key, value):
+				c.Secrets = append(c.Secrets, change)
+			default:
+				c.Options = append(c.Options, change)
+			}
+		case ChangeModified:
+			c.seen[change.Path] = true
+			c.Changed = append(c.Changed, change)
+		}
+	}
+	return nil
+}
+
+func (c *CustomConfig) ini() []string {
+	// Options grouped by section, in the order they were found
+	var sections []string
+	options := make(map[string][]Change)
+	for _, change := range c.Options {
+		if _, ok := options[change.Section]; !ok {
+			sections = append(sections, change.Section)
+		}
+		options[change.Section] = append(options[change.Section], change)
+	}
+	var lines []string
+	for _, section := range sections {
+		lines = append(lines, "["+section+"]")
+		for _, change := range options[section] {
+			lines = append(lines, fmt.Sprintf("%s = %v", change.Key, change.Old))
+		}
+	}
+	return lines
+}
+
+func (c *CustomConfig) writeComments(w io.Writer) {
+	fmt.Fprintf(w, "# customServiceConfig of %s, options set in:\n", c.Service)
+	for _, file := range c.files {
+		fmt.Fprintf(w, "#   %s\n", file)
+	}
+	fmt.Fprintf(w, "# and missing in the destination.\n")
+	lists := []struct {
+		title   string
+		changes []Change
+		format  func(Change) string
+	}{
+		{"Excluded, set for the destination deployment:", c.Excluded, func(change Change) string {
+			return fmt.Sprintf("[%s] %s = %v", change.Section, change.Key, change.Old)
+		}},
+		{"Secrets, to set from a Secret:", c.Secrets, func(change Change) string {
+			return fmt.Sprintf("[%s] %s", change.Section, change.Key)
+		}},
+		{"Set to another value in the destination, not carried over:", c.Changed, func(change Change) string {
+			return fmt.Sprintf("[%s] %s = %v, %v in the destination", change.Section, change.Key, change.Old, change.New)
+		}},
+	}
+	for _, list := range lists {
+		if len(list.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# %s\n", list.title)
+		for _, change := range list.changes {
+			fmt.Fprintf(w, "#   %s\n", list.format(change))
+		}
+	}
+}
+
+// Write writes the customServiceConfig block, or the patch of the
+// OpenStackControlPlane setting it, to apply with oc patch --type=merge.
+func (c *CustomConfig) Write(w io.Writer, format string) error {
+	indent := ""
+	switch format {
+	case "", SnippetFormat:
+	case CRPatchFormat:
+		indent = "      "
+	default:
+		return fmt.Errorf("Unknown custom config format: %s, expected snippet or patch", format)
+	}
+	c.writeComments(w)
+	if format == CRPatchFormat {
+		fmt.Fprintf(w, "spec:\n  %s:\n    template:\n", c.Service)
+	}
+	lines := c.ini()
+	if len(lines) == 0 {
+		_, err := fmt.Fprintf(w, "%scustomServiceConfig: \"\"\n", indent)
+		return err
+	}
+	fmt.Fprintf(w, "%scustomServiceConfig: |\n", indent)
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", indent, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomConfigExcluded(t *testing.T) {
+	c := CustomConfig{Service: "nova", Excludes: []string{"vnc.*", "cpu-*"}}
+	tests := []struct {
+		section  string
+		key      string
+		excluded bool
+	}{
+		{section: "DEFAULT", key: "transport_url", excluded: true},
+		{section: "DEFAULT", key: "Transport-URL", excluded: true},
+		{section: "database", key: "connection", excluded: true},
+		{section: "api_database", key: "slave_connection", excluded: true},
+		{section: "DEFAULT", key: "osapi_compute_listen", excluded: true},
+		{section: "vnc", key: "enabled", excluded: true},
+		{section: "DEFAULT", key: "cpu_allocation_ratio", excluded: true},
+		{section: "DEFAULT", key: "hostname"},
+		{section: "DEFAULT", key: "vnc_enabled"},
+		{section: "DEFAULT", key: "debug"},
+	}
+	for _, test := range tests {
+		t.Run(test.section+"."+test.key, func(t *testing.T) {
+			change := iniChange(test.section, test.key, ChangeRemoved, "value", nil)
+			if got := c.excluded(change); got != test.excluded {
+				t.Errorf("excluded() = %v, want %v", got, test.excluded)
+			}
+		})
+	}
+}
+
+func TestCustomConfigCollect(t *testing.T) {
+	defer SetRedactor(currentRedactor())
+	SetRedactor(newTestRedactor(t))
+	root := writeTree(t, map[string]string{
+		"origin/nova/controller-0/nova.conf": "[DEFAULT]\ndebug = true\nworkers = 4\ntransport_url = rabbit://h1/\n" +
+			"[vnc]\nenabled = true\n[ldap]\nldap_bind = secret\n",
+		"origin/nova/controller-1/nova.conf":      "[DEFAULT]\ndebug = false\ncpu_allocation_ratio = 2.0\n",
+		"origin/nova/controller-0/nova-api.conf":  "[DEFAULT]\nworkers = 8\n",
+		"destination/nova/controller-0/nova.conf": "[DEFAULT]\nworkers = 2\n",
+	})
+	c := CustomConfig{Service: "nova"}
+	if err := c.Collect(filepath.Join(root, "origin"), filepath.Join(root, "destination")); err != nil {
+		t.Fatal(err)
+	}
+	lists := []struct {
+		name    string
+		changes []Change
+		want    string
+	}{
+		{name: "Options", changes: c.Options, want: "removed DEFAULT.debug; removed vnc.enabled; removed DEFAULT.cpu_allocation_ratio"},
+		{name: "Excluded", changes: c.Excluded, want: "removed DEFAULT.transport_url"},
+		{name: "Secrets", changes: c.Secrets, want: "removed ldap.ldap_bind"},
+		{name: "Changed", changes: c.Changed, want: "changed DEFAULT.workers"},
+	}
+	for _, list := range lists {
+		if got := describeChanges(list.changes); got != list.want {
+			t.Errorf("%s = %q, want %q", list.name, got, list.want)
+		}
+	}
+
+	c = CustomConfig{Service: "glance"}
+	if err := c.Collect(filepath.Join(root, "origin"), filepath.Join(root, "destination")); err == nil {
+		t.Errorf("Collect() without glance.conf succeeded, want an error")
+	}
+	c = CustomConfig{Service: "nova", Excludes: []string{"["}}
+	if err := c.Collect(filepath.Join(root, "origin"), filepath.Join(root, "destination")); err == nil {
+		t.Errorf("Collect() with an invalid pattern succeeded, want an error")
+	}
+}
+
+func TestCustomConfigWrite(t *testing.T) {
+	c := CustomConfig{
+		Service: "nova",
+		Options: []Change{
+			iniChange("DEFAULT", "debug", ChangeRemoved, "true", nil),
+			iniChange("vnc", "enabled", ChangeRemoved, "true", nil),
+			iniChange("DEFAULT", "workers", ChangeRemoved, "4", nil),
+		},
+		Secrets: []Change{iniChange("ldap", "ldap_bind", ChangeRemoved, "secret", nil)},
+		Changed: []Change{iniChange("DEFAULT", "cpu_allocation_ratio", ChangeModified, "2.0", "4.0")},
+		files:   []string{"nova.conf"},
+	}
+	comments := `# customServiceConfig of nova, options set in:
+#   nova.conf
+# and missing in the destination.
+# Secrets, to set from a Secret:
+#   [ldap] ldap_bind
+# Set to another value in the destination, not carried over:
+#   [DEFAULT] cpu_allocation_ratio = 2.0, 4.0 in the destination
+`
+	tests := []struct {
+		name   string
+		config CustomConfig
+		format string
+		want   string
+	}{
+		{name: "snippet", config: c, format: SnippetFormat, want: comments + `customServiceConfig: |
+  [DEFAULT]
+  debug = true
+  workers = 4
+  [vnc]
+  enabled = true
+`},
+		{name: "patch", config: c, format: CRPatchFormat, want: comments + `spec:
+  nova:
+    template:
+      customServiceConfig: |
+        [DEFAULT]
+        debug = true
+        workers = 4
+        [vnc]
+        enabled = true
+`},
+		{name: "no options", config: CustomConfig{Service: "nova"}, want: `# customServiceConfig of nova, options set in:
+# and missing in the destination.
+customServiceConfig: ""
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.config.Write(&buf, test.format); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.want {
+				t.Errorf("Write() = %q, want %q", buf.String(), test.want)
+			}
+		})
+	}
+	if err := c.Write(&bytes.Buffer{}, "json"); err == nil {
+		t.Errorf("Write() in an unknown format succeeded, want an error")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/go-yaml/yaml"
+)
+
+// Weights of each hint in the detection confidence, out of 100.
+const (
+	knownNameConfidence = 40
+	extensionConfidence = 30
+	contentConfidence   = 20
+	parseConfidence     = 30
+)
+
+// Files whose name alone tells their type.
+var wellKnownFiles = map[string]string{
+	"my.cnf":             IniType,
+	"api-paste.ini":      IniType,
+	"logging.conf":       IniType,
+	"rootwrap.conf":      IniType,
+	"policy.yaml":        YamlType,
+	"policy.json":        JsonType,
+	"config.json":        JsonType,
+	"clouds.yaml":        YamlType,
+	"kustomization.yaml": YamlType,
+}
+
+var extensionTypes = map[string]string{
+	".ini":  IniType,
+	".conf": IniType,
+	".cnf":  IniType,
+	".cfg":  IniType,
+	".json": JsonType,
+	".yaml": YamlType,
+	".yml":  YamlType,
+}
+
+var (
+	iniSectionLine = regexp.MustCompile(`^\[[^\[\]]+\]$`)
+	iniOptionLine  = regexp.MustCompile(`^[\w.-]+\s*=`)
+	yamlKeyLine    = regexp.MustCompile(`^["']?[\w./-]+["']?:(\s|$)`)
+)
+
+func nameConfidence(path string, fileType string) int {
+	confidence := 0
+	if wellKnownFiles[filepath.Base(path)] == fileType {
+		confidence += knownNameConfidence
+	}
+	if extensionTypes[strings.ToLower(filepath.Ext(path))] == fileType {
+		confidence += extensionConfidence
+	}
+	return confidence
+}
+
+func firstContentLine(data []byte) string {
+	// Skip blank lines and comments, such as a license header
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, ";") {
+			return line
+		}
+	}
+	return ""
+}
+
+func detectConfidence(path string, data []byte, fileType string, sniff func(string) bool, parse func([]byte) bool) int {
+	/*
+		Combine the file name, a sniff of the first meaningful line and a
+		parse attempt in a confidence score. Content which does not parse
+		is never of this type, an empty file is only known by its name.
+	*/
+	confidence := nameConfidence(path, fileType)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return confidence
+	}
+	if !parse(data) {
+		return 0
+	}
+	confidence += parseConfidence
+	if sniff(firstContentLine(data)) {
+		confidence += contentConfidence
+	}
+	if confidence > 100 {
+		confidence = 100
+	}
+	return confidence
+}
+
+func detectIni(path string, data []byte) int {
+	return detectConfidence(path, data, IniType,
+		func(line string) bool {
+			return iniSectionLine.MatchString(line) || iniOptionLine.MatchString(line)
+		},
+		func(data []byte) bool {
+			// go-ini reads "key: value" too, only count files with options
+			cfg, err := ini.LoadSources(ini.LoadOptions{KeyValueDelimiters: "="}, data)
+			if err != nil {
+				return false
+			}
+			for _, section := range cfg.Sections() {
+				if len(section.Keys()) > 0 {
+					return true
+				}
+			}
+			return false
+		})
+}
+
+func detectJson(path string, data []byte) int {
+	return detectConfidence(path, data, JsonType,
+		func(line string) bool {
+			return strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[")
+		},
+		json.Valid)
+}
+
+func detectYaml(path string, data []byte) int {
+	return detectConfidence(path, data, YamlType,
+		func(line string) bool {
+			return line == "---" || strings.HasPrefix(line, "- ") || yamlKeyLine.MatchString(line)
+		},
+		func(data []byte) bool {
+			// Plain text also parses as a YAML scalar, only accept documents
+			// holding a mapping or a sequence.
+			var yamlData interface{}
+			if err := yaml.Unmarshal(data, &yamlData); err != nil {
+				return false
+			}
+			switch yamlData.(type) {
+			case map[interface{}]interface{}, []interface{}:
+				return true
+			}
+			return false
+		})
+}
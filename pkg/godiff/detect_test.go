@@ -0,0 +1,57 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		data string
+		ini  int
+		json int
+		yaml int
+	}{
+		{name: "ini file", path: "nova.conf", data: "# License\n[DEFAULT]\ndebug = true\n", ini: 80, yaml: 30},
+		{name: "ini without extension", path: "nova", data: "[DEFAULT]\ndebug = true\n", ini: 50, yaml: 30},
+		{name: "well-known ini", path: "api-paste.ini", data: "[app]\nuse = egg\n", ini: 100, yaml: 30},
+		{name: "json file", path: "data.json", data: `{"a": 1}`, json: 80, yaml: 30},
+		{name: "well-known json", path: "policy.json", data: `{"a": 1}`, json: 100, yaml: 30},
+		{name: "yaml file", path: "data.yaml", data: "---\na: 1\n", yaml: 80},
+		{name: "yaml list", path: "data", data: "- a\n- b\n", yaml: 50},
+		{name: "empty yaml", path: "policy.yaml", data: "", yaml: 70},
+		{name: "empty file", path: "data", data: "\n\n"},
+		{name: "plain text", path: "README", data: "Some text\n"},
+		{name: "binary", path: "data.bin", data: "\x00\x01\x02\xff"},
+		{name: "broken json", path: "data.json", data: `{"a": `},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := []byte(test.data)
+			if got := detectIni(test.path, data); got != test.ini {
+				t.Errorf("detectIni() = %d, want %d", got, test.ini)
+			}
+			if got := detectJson(test.path, data); got != test.json {
+				t.Errorf("detectJson() = %d, want %d", got, test.json)
+			}
+			if got := detectYaml(test.path, data); got != test.yaml {
+				t.Errorf("detectYaml() = %d, want %d", got, test.yaml)
+			}
+		})
+	}
+}
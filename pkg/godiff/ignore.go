@@ -0,0 +1,158 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// IgnoreRule suppresses the expected differences matching all its criteria:
+// the service, a glob on the file path, an INI section and key, a JSON or
+// YAML path (and what is below it) or a regular expression on the values.
+// A rule with only a service or a file suppresses whole files.
+type IgnoreRule struct {
+	Service string `yaml:"service,omitempty"`
+	File    string `yaml:"file,omitempty"`
+	Section string `yaml:"section,omitempty"`
+	Key     string `yaml:"key,omitempty"`
+	Path    string `yaml:"path,omitempty"`
+	Value   string `yaml:"value,omitempty"`
+	Reason  string `yaml:"reason"`
+	value   *regexp.Regexp
+}
+
+// IgnoreRules is the content of an ignore rules file.
+type IgnoreRules struct {
+	Ignore []IgnoreRule `yaml:"ignore"`
+}
+
+// LoadIgnoreRules reads and checks the rules of the YAML file at path.
+func LoadIgnoreRules(path string) ([]IgnoreRule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + path + "'. " + err.Error())
+	}
+	var rules IgnoreRules
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", path, err)
+	}
+	if err := CompileIgnoreRules(rules.Ignore); err != nil {
+		return nil, fmt.Errorf("Invalid ignore rule in %s: %s", path, err)
+	}
+	return rules.Ignore, nil
+}
+
+// CompileIgnoreRules checks each rule has a reason and a criterion, and
+// compiles their value expressions.
+func CompileIgnoreRules(rules []IgnoreRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Reason == "" {
+			return fmt.Errorf("rule %d has no reason", i+1)
+		}
+		if rule.Service == "" && rule.File == "" && rule.Section == "" &&
+			rule.Key == "" && rule.Path == "" && rule.Value == "" {
+			return fmt.Errorf("rule %d (%s) matches everything", i+1, rule.Reason)
+		}
+		if _, err := filepath.Match(rule.File, ""); err != nil {
+			return fmt.Errorf("rule %d (%s) has an invalid file pattern: %s", i+1, rule.Reason, err)
+		}
+		if rule.Value != "" {
+			value, err := regexp.Compile(rule.Value)
+			if err != nil {
+				return fmt.Errorf("rule %d (%s) has an invalid value expression: %s", i+1, rule.Reason, err)
+			}
+			rule.value = value
+		}
+	}
+	return nil
+}
+
+func matchFile(pattern string, path string) bool {
+	/*
+		Match the pattern against as many trailing components of the path
+		as it has, so *.conf matches the file name and etc/nova/*.conf the
+		end of the path. An absolute pattern matches the whole path.
+	*/
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(pattern, "/") {
+		parts := strings.Split(path, "/")
+		if n := strings.Count(pattern, "/") + 1; n < len(parts) {
+			path = strings.Join(parts[len(parts)-n:], "/")
+		}
+	}
+	match, _ := filepath.Match(pattern, path)
+	return match
+}
+
+func (r *IgnoreRule) fileRule() bool {
+	return r.Section == "" && r.Key == "" && r.Path == "" && r.Value == ""
+}
+
+func (r *IgnoreRule) matchesFile(service string, path string) bool {
+	return fileMatches(r.Service, r.File, service, path)
+}
+
+func (r *IgnoreRule) matchesChange(change Change) bool {
+	if r.Section != "" && canonicalSection(r.Section) != change.Section {
+		return false
+	}
+	if r.Key != "" && (change.Key == "" || canonicalOption(r.Key) != canonicalOption(change.Key)) {
+		return false
+	}
+	if r.Path != "" && change.Path != r.Path &&
+		!strings.HasPrefix(change.Path, r.Path+".") && !strings.HasPrefix(change.Path, r.Path+"[") {
+		return false
+	}
+	if r.value != nil {
+		matched := false
+		for _, value := range []interface{}{change.Old, change.New} {
+			if value != nil && r.value.MatchString(displayValue(value)) {
+				matched = true
+			}
+		}
+		return matched
+	}
+	return true
+}
+
+func ignoredFile(rules []IgnoreRule, service string, path string) (string, bool) {
+	// Return the reason of the first whole file rule matching path
+	for i := range rules {
+		if rules[i].fileRule() && rules[i].matchesFile(service, path) {
+			return rules[i].Reason, true
+		}
+	}
+	return "", false
+}
+
+func ignoredChange(rules []IgnoreRule, service string, path string, change Change) (string, bool) {
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.fileRule() && rule.matchesFile(service, path) && rule.matchesChange(change) {
+			return rule.Reason, true
+		}
+	}
+	return "", false
+}
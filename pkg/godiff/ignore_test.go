@@ -0,0 +1,115 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import "testing"
+
+func TestCompileIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  IgnoreRule
+		valid bool
+	}{
+		{name: "key", rule: IgnoreRule{Key: "debug", Reason: "expected"}, valid: true},
+		{name: "value", rule: IgnoreRule{Value: "^controller-[0-9]+$", Reason: "expected"}, valid: true},
+		{name: "no reason", rule: IgnoreRule{Key: "debug"}},
+		{name: "matches everything", rule: IgnoreRule{Reason: "expected"}},
+		{name: "invalid file pattern", rule: IgnoreRule{File: "[", Reason: "expected"}},
+		{name: "invalid value", rule: IgnoreRule{Value: "(", Reason: "expected"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CompileIgnoreRules([]IgnoreRule{test.rule}); (err == nil) != test.valid {
+				t.Errorf("CompileIgnoreRules() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestMatchFile(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.conf", path: "/tmp/origin/nova/nova.conf", want: true},
+		{pattern: "nova/*.conf", path: "/tmp/origin/nova/nova.conf", want: true},
+		{pattern: "cinder/*.conf", path: "/tmp/origin/nova/nova.conf"},
+		{pattern: "*.yaml", path: "/tmp/origin/nova/nova.conf"},
+		{pattern: "/tmp/origin/nova/nova.conf", path: "/tmp/origin/nova/nova.conf", want: true},
+		{pattern: "/nova/nova.conf", path: "/tmp/origin/nova/nova.conf"},
+	}
+	for _, test := range tests {
+		if got := matchFile(test.pattern, test.path); got != test.want {
+			t.Errorf("matchFile(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestIgnoredChange(t *testing.T) {
+	ini := iniChange("DEFAULT", "max-pool-size", ChangeModified, "5", "10")
+	tree := Change{Path: "spec.containers[name=api].image", Kind: ChangeModified, Old: "quay.io/a:1", New: "quay.io/a:2"}
+	tests := []struct {
+		name    string
+		rule    IgnoreRule
+		change  Change
+		ignored bool
+	}{
+		{name: "section and key", rule: IgnoreRule{Section: "default", Key: "max_pool_size"}, change: ini, ignored: true},
+		{name: "other key", rule: IgnoreRule{Key: "max_overflow"}, change: ini},
+		{name: "key of a tree change", rule: IgnoreRule{Key: "image"}, change: tree},
+		{name: "path prefix", rule: IgnoreRule{Path: "spec.containers"}, change: tree, ignored: true},
+		{name: "path item", rule: IgnoreRule{Path: "spec.containers[name=api]"}, change: tree, ignored: true},
+		{name: "partial path name", rule: IgnoreRule{Path: "spec.container"}, change: tree},
+		{name: "new value", rule: IgnoreRule{Value: ":2$"}, change: tree, ignored: true},
+		{name: "old value", rule: IgnoreRule{Value: "^5$"}, change: ini, ignored: true},
+		{name: "no value", rule: IgnoreRule{Value: "^1$"}, change: ini},
+		{name: "file and key", rule: IgnoreRule{File: "nova/*.conf", Key: "max_pool_size"}, change: ini, ignored: true},
+		{name: "other file", rule: IgnoreRule{File: "cinder.conf", Key: "max_pool_size"}, change: ini},
+		{name: "other service", rule: IgnoreRule{Service: "cinder", Key: "max_pool_size"}, change: ini},
+		{name: "whole file rule", rule: IgnoreRule{File: "nova.conf"}, change: ini},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.rule.Reason = "expected"
+			rules := []IgnoreRule{test.rule}
+			if err := CompileIgnoreRules(rules); err != nil {
+				t.Fatal(err)
+			}
+			_, ignored := ignoredChange(rules, "nova", "/tmp/origin/nova/nova.conf", test.change)
+			if ignored != test.ignored {
+				t.Errorf("ignoredChange() = %v, want %v", ignored, test.ignored)
+			}
+		})
+	}
+}
+
+func TestIgnoredFile(t *testing.T) {
+	rules := []IgnoreRule{
+		{File: "policy.yaml", Reason: "policies differ"},
+		{File: "nova.conf", Key: "debug", Reason: "debug differs"},
+	}
+	if err := CompileIgnoreRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	if reason, ok := ignoredFile(rules, "nova", "/tmp/origin/nova/policy.yaml"); !ok || reason != "policies differ" {
+		t.Errorf("ignoredFile(policy.yaml) = %q, %v, want the policies rule", reason, ok)
+	}
+	if _, ok := ignoredFile(rules, "nova", "/tmp/origin/nova/nova.conf"); ok {
+		t.Errorf("ignoredFile(nova.conf) is ignored by an option rule")
+	}
+}
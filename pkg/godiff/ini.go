@@ -0,0 +1,68 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// iniSection gathers the keys of every section of a file sharing the same
+// canonical name, indexed by canonical option name. written is the name of
+// the first section with keys, as spelled in the file.
+type iniSection struct {
+	name    string
+	written string
+	keys    []*ini.Key
+	index   map[string]*ini.Key
+}
+
+func canonicalSection(name string) string {
+	// oslo.config only knows DEFAULT, [default] is the same section
+	if strings.EqualFold(name, ini.DefaultSection) {
+		return ini.DefaultSection
+	}
+	return name
+}
+
+func canonicalOption(name string) string {
+	// oslo.config reads max-pool-size and max_pool_size as the same option
+	return strings.Replace(name, "-", "_", -1)
+}
+
+func indexIni(cfg *ini.File) ([]*iniSection, map[string]*iniSection) {
+	var sections []*iniSection
+	index := make(map[string]*iniSection)
+	for _, sec := range cfg.Sections() {
+		name := canonicalSection(sec.Name())
+		section, ok := index[name]
+		if !ok {
+			section = &iniSection{name: name, index: make(map[string]*ini.Key)}
+			index[name] = section
+			sections = append(sections, section)
+		}
+		if section.written == "" && len(sec.Keys()) > 0 {
+			section.written = sec.Name()
+		}
+		for _, key := range sec.Keys() {
+			section.keys = append(section.keys, key)
+			section.index[canonicalOption(key.Name())] = key
+		}
+	}
+	return sections, index
+}
@@ -0,0 +1,246 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/go-yaml/yaml"
+)
+
+// Mapping translates the origin configuration into the destination layout
+// before comparing: INI sections and options are renamed, then values are
+// rewritten by regular expressions and host names are replaced.
+type Mapping struct {
+	Rename  []RenameRule      `yaml:"rename,omitempty"`
+	Rewrite []RewriteRule     `yaml:"rewrite,omitempty"`
+	Hosts   map[string]string `yaml:"hosts,omitempty"`
+	hosts   []string
+}
+
+// RenameRule moves the INI option Key of Section, or the whole section when
+// Key is empty, to ToSection and ToKey. Either defaults to the origin name.
+type RenameRule struct {
+	Service   string `yaml:"service,omitempty"`
+	File      string `yaml:"file,omitempty"`
+	Section   string `yaml:"section"`
+	Key       string `yaml:"key,omitempty"`
+	ToSection string `yaml:"to_section,omitempty"`
+	ToKey     string `yaml:"to_key,omitempty"`
+}
+
+// RewriteRule replaces the matches of Match by Replace, which can refer to
+// the groups of Match as $1. With a Section or a Key the rule only applies
+// to INI options, otherwise to every value.
+type RewriteRule struct {
+	Service string `yaml:"service,omitempty"`
+	File    string `yaml:"file,omitempty"`
+	Section string `yaml:"section,omitempty"`
+	Key     string `yaml:"key,omitempty"`
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+	match   *regexp.Regexp
+}
+
+// LoadMapping reads and checks the mapping YAML file at path.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + path + "'. " + err.Error())
+	}
+	mapping := &Mapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", path, err)
+	}
+	if err := mapping.Compile(); err != nil {
+		return nil, fmt.Errorf("Invalid mapping in %s: %s", path, err)
+	}
+	return mapping, nil
+}
+
+// Compile checks the rules and compiles their expressions.
+func (m *Mapping) Compile() error {
+	for i, rule := range m.Rename {
+		if rule.Section == "" {
+			return fmt.Errorf("rename %d has no section", i+1)
+		}
+		if rule.ToSection == "" && rule.ToKey == "" {
+			return fmt.Errorf("rename %d has no to_section or to_key", i+1)
+		}
+		if rule.Key == "" && rule.ToKey != "" {
+			return fmt.Errorf("rename %d renames a key of a whole section", i+1)
+		}
+	}
+	for i := range m.Rewrite {
+		rule := &m.Rewrite[i]
+		if rule.Match == "" {
+			return fmt.Errorf("rewrite %d has no match", i+1)
+		}
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return fmt.Errorf("rewrite %d has an invalid expression: %s", i+1, err)
+		}
+		rule.match = match
+	}
+	// Replace the longest names first, for a stable result
+	hosts := make([]string, 0, len(m.Hosts))
+	for host := range m.Hosts {
+		if host == "" {
+			return errors.New("hosts has an empty name")
+		}
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if len(hosts[i]) != len(hosts[j]) {
+			return len(hosts[i]) > len(hosts[j])
+		}
+		return hosts[i] < hosts[j]
+	})
+	m.hosts = hosts
+	return nil
+}
+
+func fileMatches(ruleService string, ruleFile string, service string, path string) bool {
+	if ruleService != "" && ruleService != service {
+		return false
+	}
+	return ruleFile == "" || matchFile(ruleFile, path)
+}
+
+func (m *Mapping) rewrite(service string, path string, section string, key string, value string) string {
+	// INI options are given with their section and key, other values without
+	for _, rule := range m.Rewrite {
+		if !fileMatches(rule.Service, rule.File, service, path) {
+			continue
+		}
+		if (rule.Section != "" || rule.Key != "") && key == "" {
+			continue
+		}
+		if rule.Section != "" && canonicalSection(rule.Section) != canonicalSection(section) {
+			continue
+		}
+		if rule.Key != "" && canonicalOption(rule.Key) != canonicalOption(key) {
+			continue
+		}
+		value = rule.match.ReplaceAllString(value, rule.Replace)
+	}
+	for _, host := range m.hosts {
+		value = replaceHost(value, host, m.Hosts[host])
+	}
+	return value
+}
+
+func isHostChar(c byte) bool {
+	return c == '.' || c == '-' || c == '_' ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func replaceHost(value string, host string, replace string) string {
+	// Replace host where it is a whole name, not a part of a longer one
+	var b strings.Builder
+	for {
+		i := strings.Index(value, host)
+		if i < 0 {
+			b.WriteString(value)
+			return b.String()
+		}
+		end := i + len(host)
+		if (i == 0 || !isHostChar(value[i-1])) && (end == len(value) || !isHostChar(value[end])) {
+			b.WriteString(value[:i])
+			b.WriteString(replace)
+		} else {
+			b.WriteString(value[:end])
+		}
+		value = value[end:]
+	}
+}
+
+func (m *Mapping) renameIni(cfg *ini.File, rule RenameRule) {
+	for _, sec := range cfg.Sections() {
+		if canonicalSection(sec.Name()) != canonicalSection(rule.Section) {
+			continue
+		}
+		toSection := rule.ToSection
+		if toSection == "" {
+			toSection = sec.Name()
+		}
+		moved := false
+		for _, key := range sec.Keys() {
+			if rule.Key != "" && canonicalOption(rule.Key) != canonicalOption(key.Name()) {
+				continue
+			}
+			toKey := rule.ToKey
+			if toKey == "" {
+				toKey = key.Name()
+			}
+			if toSection == sec.Name() && toKey == key.Name() {
+				continue
+			}
+			log.Info("Mapping origin option: [", sec.Name(), "] ", key.Name(), " to: [", toSection, "] ", toKey)
+			value := key.Value()
+			sec.DeleteKey(key.Name())
+			cfg.Section(toSection).NewKey(toKey, value)
+			moved = true
+		}
+		// Drop the section left empty by the move, it is not in the destination
+		if moved && toSection != sec.Name() && len(sec.Keys()) == 0 && sec.Name() != ini.DefaultSection {
+			cfg.DeleteSection(sec.Name())
+		}
+	}
+}
+
+func (m *Mapping) applyIni(cfg *ini.File, service string, path string) {
+	for _, rule := range m.Rename {
+		if fileMatches(rule.Service, rule.File, service, path) {
+			m.renameIni(cfg, rule)
+		}
+	}
+	for _, sec := range cfg.Sections() {
+		for _, key := range sec.Keys() {
+			if value := m.rewrite(service, path, sec.Name(), key.Name(), key.Value()); value != key.Value() {
+				key.SetValue(value)
+			}
+		}
+	}
+}
+
+func (m *Mapping) applyTree(value interface{}, service string, path string) interface{} {
+	// Rewrite the strings of a JSON or YAML document
+	switch v := value.(type) {
+	case string:
+		return m.rewrite(service, path, "", "", v)
+	case yaml.MapSlice:
+		mapped := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			mapped[i] = yaml.MapItem{Key: item.Key, Value: m.applyTree(item.Value, service, path)}
+		}
+		return mapped
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			mapped[i] = m.applyTree(item, service, path)
+		}
+		return mapped
+	}
+	return value
+}
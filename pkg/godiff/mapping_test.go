@@ -0,0 +1,131 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+func TestMappingCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		valid   bool
+	}{
+		{name: "rename option", mapping: Mapping{Rename: []RenameRule{{Section: "DEFAULT", Key: "a", ToKey: "b"}}}, valid: true},
+		{name: "rename section", mapping: Mapping{Rename: []RenameRule{{Section: "old", ToSection: "new"}}}, valid: true},
+		{name: "rename without section", mapping: Mapping{Rename: []RenameRule{{Key: "a", ToKey: "b"}}}},
+		{name: "rename to nothing", mapping: Mapping{Rename: []RenameRule{{Section: "DEFAULT", Key: "a"}}}},
+		{name: "key of a section", mapping: Mapping{Rename: []RenameRule{{Section: "old", ToKey: "b"}}}},
+		{name: "rewrite", mapping: Mapping{Rewrite: []RewriteRule{{Match: "^(.*):5672$", Replace: "$1:5671"}}}, valid: true},
+		{name: "rewrite without match", mapping: Mapping{Rewrite: []RewriteRule{{Replace: "x"}}}},
+		{name: "invalid rewrite", mapping: Mapping{Rewrite: []RewriteRule{{Match: "(", Replace: "x"}}}},
+		{name: "empty host", mapping: Mapping{Hosts: map[string]string{"": "x"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.mapping.Compile(); (err == nil) != test.valid {
+				t.Errorf("Compile() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestReplaceHost(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "controller-0", want: "rabbitmq"},
+		{value: "rabbit://guest@controller-0:5672/", want: "rabbit://guest@rabbitmq:5672/"},
+		{value: "controller-0,controller-0", want: "rabbitmq,rabbitmq"},
+		{value: "controller-01", want: "controller-01"},
+		{value: "overcloud-controller-0.example", want: "overcloud-controller-0.example"},
+	}
+	for _, test := range tests {
+		if got := replaceHost(test.value, "controller-0", "rabbitmq"); got != test.want {
+			t.Errorf("replaceHost(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestMappingIni(t *testing.T) {
+	mapping := &Mapping{
+		Rename: []RenameRule{
+			{Section: "DEFAULT", Key: "rpc_backend", ToSection: "oslo_messaging", ToKey: "driver"},
+			{Section: "old_cache", ToSection: "cache"},
+		},
+		Rewrite: []RewriteRule{{Section: "DEFAULT", Key: "transport_url", Match: ":5672/", Replace: ":5671/"}},
+		Hosts:   map[string]string{"controller-0": "rabbitmq.openstack.svc"},
+	}
+	if err := mapping.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		org     string
+		dest    string
+		changes string
+	}{
+		{name: "renamed option", org: "[DEFAULT]\nrpc-backend = rabbit\n", dest: "[oslo_messaging]\ndriver = rabbit\n"},
+		{name: "renamed section", org: "[old_cache]\nenabled = true\n", dest: "[cache]\nenabled = true\n"},
+		{name: "rewritten value and host", org: "[DEFAULT]\ntransport_url = rabbit://controller-0:5672/\n",
+			dest: "[DEFAULT]\ntransport_url = rabbit://rabbitmq.openstack.svc:5671/\n"},
+		{name: "still different", org: "[DEFAULT]\nrpc_backend = rabbit\n", dest: "[oslo_messaging]\ndriver = kafka\n",
+			changes: "changed oslo_messaging.driver"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Origin: "org.conf", Destination: "dest.conf", Mapping: mapping}
+			if err := f.compareIni([]byte(test.org), []byte(test.dest), f.Origin, f.Destination); err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(f.Changes); got != test.changes {
+				t.Errorf("Changes = %q, want %q", got, test.changes)
+			}
+		})
+	}
+}
+
+func TestMappingTree(t *testing.T) {
+	mapping := &Mapping{
+		Rewrite: []RewriteRule{
+			{Match: "^quay.io/tripleo", Replace: "quay.io/podified"},
+			{Key: "debug", Match: "True", Replace: "true"},
+		},
+		Hosts: map[string]string{"controller-0": "keystone-internal"},
+	}
+	if err := mapping.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	value := yaml.MapSlice{
+		{Key: "image", Value: "quay.io/tripleo/keystone:1"},
+		{Key: "debug", Value: "True"},
+		{Key: "endpoints", Value: []interface{}{"http://controller-0:5000", 5000}},
+	}
+	want := yaml.MapSlice{
+		{Key: "image", Value: "quay.io/podified/keystone:1"},
+		{Key: "debug", Value: "True"},
+		{Key: "endpoints", Value: []interface{}{"http://keystone-internal:5000", 5000}},
+	}
+	if got := mapping.applyTree(value, "keystone", "keystone.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("applyTree() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,373 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/go-yaml/yaml"
+)
+
+// Option types, as named by oslo-config-generator.
+const (
+	BoolOption   = "boolean value"
+	IntOption    = "integer value"
+	PortOption   = "port value"
+	FloatOption  = "floating point value"
+	ListOption   = "list value"
+	DictOption   = "dict value"
+	StringOption = "string value"
+)
+
+// DefaultValueReason is the ignore reason of an option missing on one side
+// with its default value on the other.
+const DefaultValueReason = "equal to the default value"
+
+// OptionMetadata describes an option the way oslo-config-generator writes
+// it with --format yaml or json.
+type OptionMetadata struct {
+	Name                 string             `yaml:"name"`
+	Type                 string             `yaml:"type"`
+	Default              interface{}        `yaml:"default"`
+	Secret               bool               `yaml:"secret"`
+	DeprecatedOpts       []DeprecatedOption `yaml:"deprecated_opts"`
+	DeprecatedForRemoval bool               `yaml:"deprecated_for_removal"`
+	DeprecatedReason     string             `yaml:"deprecated_reason"`
+	DeprecatedSince      string             `yaml:"deprecated_since"`
+	section              string
+}
+
+// DeprecatedOption is a former name of an option. An empty group or name
+// stands for the group or name of the option.
+type DeprecatedOption struct {
+	Group string `yaml:"group"`
+	Name  string `yaml:"name"`
+}
+
+type generatorGroup struct {
+	Opts []OptionMetadata `yaml:"opts"`
+}
+
+type generatorOutput struct {
+	Options map[string]generatorGroup `yaml:"options"`
+}
+
+// fileOptions are the options of a configuration file, indexed by
+// canonical section and option names, and by deprecated names.
+type fileOptions struct {
+	sections   map[string]map[string]*OptionMetadata
+	deprecated map[string]map[string]*OptionMetadata
+}
+
+// Metadata holds the option definitions of the configuration files, read
+// from the oslo-config-generator files of a directory: <service>/<name>.yaml
+// describes the <name>.* files of a service, <name>.yaml those of any
+// service. JSON files are read the same way.
+type Metadata struct {
+	files map[string]*fileOptions
+}
+
+// LoadMetadataDir reads the oslo-config-generator files found in dir.
+func LoadMetadataDir(dir string) (*Metadata, error) {
+	m := &Metadata{files: make(map[string]*fileOptions)}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if info.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			return nil
+		}
+		relPath, _ := filepath.Rel(dir, path)
+		name := strings.TrimSuffix(filepath.ToSlash(relPath), ext)
+		if strings.Count(name, "/") > 1 {
+			return nil
+		}
+		options, err := loadGeneratorFile(path)
+		if err != nil {
+			return err
+		}
+		m.files[name] = options
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func loadGeneratorFile(path string) (*fileOptions, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + path + "'. " + err.Error())
+	}
+	// JSON documents are read as YAML
+	var output generatorOutput
+	if err := yaml.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", path, err)
+	}
+	options := &fileOptions{
+		sections:   make(map[string]map[string]*OptionMetadata),
+		deprecated: make(map[string]map[string]*OptionMetadata),
+	}
+	for group, opts := range output.Options {
+		section := canonicalSection(group)
+		for i := range opts.Opts {
+			opt := &opts.Opts[i]
+			if opt.Name == "" {
+				return nil, fmt.Errorf("Option without name in %s, section %s", path, group)
+			}
+			opt.section = section
+			addOption(options.sections, section, opt.Name, opt)
+			for _, old := range opt.DeprecatedOpts {
+				oldSection, oldName := old.Group, old.Name
+				if oldSection == "" {
+					oldSection = section
+				}
+				if oldName == "" {
+					oldName = opt.Name
+				}
+				addOption(options.deprecated, canonicalSection(oldSection), oldName, opt)
+			}
+		}
+	}
+	return options, nil
+}
+
+func addOption(index map[string]map[string]*OptionMetadata, section string, name string, opt *OptionMetadata) {
+	if index[section] == nil {
+		index[section] = make(map[string]*OptionMetadata)
+	}
+	index[section][canonicalOption(name)] = opt
+}
+
+func (m *Metadata) options(service string, path string) *fileOptions {
+	if m == nil {
+		return nil
+	}
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if options, ok := m.files[service+"/"+name]; ok && service != "" {
+		return options
+	}
+	return m.files[name]
+}
+
+// SecretPatterns returns regular expressions matching the names of the
+// options declared secret, to be masked along with the default ones.
+func (m *Metadata) SecretPatterns() []string {
+	names := make(map[string]bool)
+	for _, options := range m.files {
+		for _, section := range options.sections {
+			for name, opt := range section {
+				if opt.Secret {
+					names[strings.ToLower(name)] = true
+				}
+			}
+		}
+	}
+	var patterns []string
+	for name := range names {
+		patterns = append(patterns, "^"+regexp.QuoteMeta(name)+"$")
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+func (o *fileOptions) knowsSection(section string) bool {
+	return o != nil && (o.sections[section] != nil || o.deprecated[section] != nil)
+}
+
+func (o *fileOptions) lookup(section string, name string) (*OptionMetadata, bool) {
+	// Options are found by their name, then by a deprecated name
+	if o == nil {
+		return nil, false
+	}
+	name = canonicalOption(name)
+	if opt, ok := o.sections[section][name]; ok {
+		return opt, true
+	}
+	opt, ok := o.deprecated[section][name]
+	return opt, ok
+}
+
+func (o *fileOptions) current(section string, name string) (*OptionMetadata, bool) {
+	if o == nil {
+		return nil, false
+	}
+	opt, ok := o.sections[section][canonicalOption(name)]
+	return opt, ok
+}
+
+func (o *fileOptions) status(section string, name string, origin *fileOptions) string {
+	/*
+		Return why an option needs attention in the destination release:
+		deprecated, known by the origin release only, or unknown. Options
+		of the sections the metadata does not describe are not reported,
+		such as the backend sections named by the operator.
+	*/
+	if o == nil {
+		return ""
+	}
+	if opt, ok := o.current(section, name); ok {
+		if !opt.DeprecatedForRemoval {
+			return ""
+		}
+		if opt.DeprecatedSince != "" {
+			return "deprecated for removal since " + opt.DeprecatedSince
+		}
+		return "deprecated for removal"
+	}
+	if opt, ok := o.lookup(section, name); ok {
+		return fmt.Sprintf("deprecated name, replaced by %s.%s", opt.section, opt.Name)
+	}
+	if _, ok := origin.lookup(section, name); ok {
+		return "removed in the destination release"
+	}
+	if o.knowsSection(section) {
+		return "unknown option"
+	}
+	return ""
+}
+
+func (o *fileOptions) optionNotes(sections []*iniSection, path string, side string, origin *fileOptions) []string {
+	// Log and return the options of a file needing attention
+	var notes []string
+	for _, section := range sections {
+		for _, key := range section.keys {
+			status := o.status(section.name, key.Name(), origin)
+			if status == "" {
+				continue
+			}
+			if opt, ok := o.current(section.name, key.Name()); ok && opt.DeprecatedReason != "" {
+				status += ": " + strings.TrimSpace(opt.DeprecatedReason)
+			}
+			log.Warn("Option: ", section.name, ".", key.Name(), " in: ", path, ": ", status)
+			notes = append(notes, fmt.Sprintf("option %s.%s in %s: %s", section.name, key.Name(), side, status))
+		}
+	}
+	return notes
+}
+
+// iniOption is an option found in a file.
+type iniOption struct {
+	section string
+	key     *ini.Key
+}
+
+func (o *fileOptions) keysByOption(sections []*iniSection) map[*OptionMetadata]iniOption {
+	keys := make(map[*OptionMetadata]iniOption)
+	for _, section := range sections {
+		for _, key := range section.keys {
+			if opt, ok := o.lookup(section.name, key.Name()); ok {
+				keys[opt] = iniOption{section: section.name, key: key}
+			}
+		}
+	}
+	return keys
+}
+
+func (o *fileOptions) valuesEqual(section string, name string, a string, b string) bool {
+	opt, ok := o.lookup(section, name)
+	if !ok {
+		return osloValuesEqual(a, b)
+	}
+	return a == b || normalizeTypedValue(opt.Type, a) == normalizeTypedValue(opt.Type, b)
+}
+
+func (o *fileOptions) isDefault(section string, name string, value string) bool {
+	opt, ok := o.lookup(section, name)
+	if !ok {
+		return false
+	}
+	defaultValue, ok := formatDefault(opt.Default)
+	return ok && normalizeTypedValue(opt.Type, value) == normalizeTypedValue(opt.Type, defaultValue)
+}
+
+func formatDefault(value interface{}) (string, bool) {
+	// Defaults are written as YAML values, write them as INI values
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), true
+	case map[interface{}]interface{}:
+		var items []string
+		for key, item := range v {
+			items = append(items, fmt.Sprintf("%v:%v", key, item))
+		}
+		return strings.Join(items, ","), true
+	}
+	return fmt.Sprint(value), true
+}
+
+func normalizeTypedValue(optType string, value string) string {
+	/*
+		Return the canonical form of an INI value of the given option
+		type. Strings are kept as they are, values of unknown types are
+		normalized as guessed by normalizeOsloValue.
+	*/
+	value = unquote(strings.TrimSpace(value))
+	switch optType {
+	case BoolOption:
+		if b, ok := parseOsloBool(value); ok {
+			return strconv.FormatBool(b)
+		}
+	case IntOption, PortOption:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+	case FloatOption:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case ListOption, DictOption:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if optType == DictOption {
+				pair := strings.SplitN(item, ":", 2)
+				if len(pair) == 2 {
+					item = strings.TrimSpace(pair[0]) + ":" + strings.TrimSpace(pair[1])
+				}
+			}
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+		sort.Strings(items)
+		return "[" + strings.Join(items, ",") + "]"
+	case StringOption:
+		return value
+	case "", "unknown value":
+		return normalizeOsloValue(value)
+	}
+	return value
+}
@@ -0,0 +1,221 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeMetadata(t *testing.T, content string) *Metadata {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "keystone.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadMetadataDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCheckOptions(t *testing.T) {
+	metadata := writeMetadata(t, `options:
+  DEFAULT:
+    opts:
+    - name: debug
+      type: boolean value
+    - name: rabbit_ha_queues
+      type: boolean value
+      deprecated_for_removal: true
+      deprecated_since: Yoga
+`)
+	originMetadata := writeMetadata(t, `options:
+  DEFAULT:
+    opts:
+    - name: bogus_opt
+      type: string value
+`)
+	path := filepath.Join(t.TempDir(), "keystone.conf")
+	content := "[DEFAULT]\ndebug = true\nbogus_opt = 1\nrabbit_ha_queues = true\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := CompareFileNames{Origin: path, Destination: path, Metadata: metadata, OriginMetadata: originMetadata}
+	if err := f.CheckOptions(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"option DEFAULT.bogus_opt in origin and destination: removed in the destination release",
+		"option DEFAULT.rabbit_ha_queues in origin and destination: deprecated for removal since Yoga",
+	}
+	if !reflect.DeepEqual(f.Notes, want) {
+		t.Errorf("Notes = %q, want %q", f.Notes, want)
+	}
+}
+
+func TestNormalizeTypedValue(t *testing.T) {
+	tests := []struct {
+		optType string
+		a       string
+		b       string
+		equal   bool
+	}{
+		{optType: BoolOption, a: "True", b: "yes", equal: true},
+		{optType: BoolOption, a: "true", b: "0"},
+		{optType: IntOption, a: "010", b: "10", equal: true},
+		{optType: PortOption, a: "5672", b: "5673"},
+		{optType: FloatOption, a: "1.50", b: "1.5", equal: true},
+		{optType: ListOption, a: "a, b", b: "b,a", equal: true},
+		{optType: ListOption, a: "a,b", b: "a,c"},
+		{optType: DictOption, a: "a: 1, b:2", b: "b:2,a:1", equal: true},
+		{optType: StringOption, a: "True", b: "true"},
+		{optType: StringOption, a: `"value"`, b: "value", equal: true},
+		{optType: "", a: "True", b: "true", equal: true},
+	}
+	for _, test := range tests {
+		t.Run(test.optType+" "+test.a+" "+test.b, func(t *testing.T) {
+			a, b := normalizeTypedValue(test.optType, test.a), normalizeTypedValue(test.optType, test.b)
+			if (a == b) != test.equal {
+				t.Errorf("normalizeTypedValue() = %q and %q, want equal %v", a, b, test.equal)
+			}
+		})
+	}
+}
+
+func TestLoadMetadataDir(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"nova.yaml": `options:
+  DEFAULT:
+    opts:
+    - name: workers
+      type: integer value
+`,
+		"cinder/nova.json": `{"options": {"DEFAULT": {"opts": [{"name": "password", "type": "string value", "secret": true}]}}}`,
+		"keystone.yaml": `options:
+  database:
+    opts:
+    - name: connection
+      type: string value
+      secret: true
+      deprecated_opts:
+      - group: sql
+      - name: sql_connection
+`,
+		"notes.txt":         "not metadata",
+		"a/b/keystone.yaml": "not: [read",
+	})
+	m, err := LoadMetadataDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		service string
+		path    string
+		section string
+		option  string
+		want    string
+	}{
+		{name: "any service", service: "nova", path: "nova.conf", section: "DEFAULT", option: "workers", want: "workers"},
+		{name: "service file first", service: "cinder", path: "nova.conf", section: "DEFAULT", option: "password", want: "password"},
+		{name: "service file only", service: "cinder", path: "nova.conf", section: "DEFAULT", option: "workers"},
+		{name: "deprecated group", service: "keystone", path: "keystone.conf", section: "sql", option: "connection", want: "connection"},
+		{name: "deprecated name", service: "keystone", path: "keystone.conf", section: "database", option: "sql-connection", want: "connection"},
+		{name: "unknown file", service: "nova", path: "api-paste.ini", section: "DEFAULT", option: "workers"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opt, ok := m.options(test.service, test.path).lookup(test.section, test.option)
+			got := ""
+			if ok {
+				got = opt.Name
+			}
+			if got != test.want {
+				t.Errorf("lookup() = %q, want %q", got, test.want)
+			}
+		})
+	}
+	want := []string{"^connection$", "^password$"}
+	if got := m.SecretPatterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SecretPatterns() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadMetadataErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"nameless option": "options:\n  DEFAULT:\n    opts:\n    - type: string value\n",
+		"invalid yaml":    "options: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := ioutil.WriteFile(filepath.Join(dir, "nova.yaml"), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMetadataDir(dir); err == nil {
+				t.Errorf("LoadMetadataDir() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCompareIniMetadata(t *testing.T) {
+	metadata := writeMetadata(t, `options:
+  DEFAULT:
+    opts:
+    - name: debug
+      type: boolean value
+      default: false
+    - name: workers
+      type: integer value
+    - name: hosts
+      type: list value
+      default: [a, b]
+    - name: name
+      type: string value
+`)
+	tests := []struct {
+		name    string
+		org     string
+		dest    string
+		changes string
+		ignored string
+	}{
+		{name: "typed values", org: "debug = yes\nworkers = 04\n", dest: "debug = True\nworkers = 4\n"},
+		{name: "string values", org: "name = True\n", dest: "name = true\n", changes: "changed DEFAULT.name"},
+		{name: "default value", org: "debug = false\nhosts = b, a\n", dest: "", ignored: "removed DEFAULT.debug; removed DEFAULT.hosts"},
+		{name: "other value", org: "debug = true\n", dest: "", changes: "removed DEFAULT.debug"},
+		{name: "no default", org: "", dest: "workers = 1\n", changes: "added DEFAULT.workers"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Origin: "keystone.conf", Destination: "keystone.conf", Metadata: metadata}
+			org, dest := "[DEFAULT]\n"+test.org, "[DEFAULT]\n"+test.dest
+			if err := f.compareIni([]byte(org), []byte(dest), f.Origin, f.Destination); err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(f.Changes); got != test.changes {
+				t.Errorf("Changes = %q, want %q", got, test.changes)
+			}
+			if got := describeChanges(f.Ignored); got != test.ignored {
+				t.Errorf("Ignored = %q, want %q", got, test.ignored)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if first == last && (first == '"' || first == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+func parseOsloBool(value string) (bool, bool) {
+	// Same spellings as oslo.config BoolOpt
+	switch strings.ToLower(value) {
+	case "true", "1", "on", "yes":
+		return true, true
+	case "false", "0", "off", "no":
+		return false, true
+	}
+	return false, false
+}
+
+func normalizeOsloScalar(value string) string {
+	value = unquote(strings.TrimSpace(value))
+	if b, ok := parseOsloBool(value); ok {
+		return strconv.FormatBool(b)
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return value
+}
+
+func normalizeOsloValue(value string) string {
+	/*
+		Return the canonical form of an INI value the way oslo.config would
+		read it: booleans, integers and floats in one spelling, quotes
+		dropped, ListOpt items and DictOpt k1:v1,k2:v2 pairs sorted.
+	*/
+	value = unquote(strings.TrimSpace(value))
+	if !strings.Contains(value, ",") && (!strings.Contains(value, ":") || strings.Contains(value, "://")) {
+		return normalizeOsloScalar(value)
+	}
+
+	var items []string
+	isDict := true
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, ":") || strings.Contains(item, "://") {
+			isDict = false
+		}
+		items = append(items, item)
+	}
+	for i, item := range items {
+		if isDict {
+			pair := strings.SplitN(item, ":", 2)
+			items[i] = normalizeOsloScalar(pair[0]) + ":" + normalizeOsloScalar(pair[1])
+		} else {
+			items[i] = normalizeOsloScalar(item)
+		}
+	}
+	sort.Strings(items)
+	if isDict {
+		return "{" + strings.Join(items, ",") + "}"
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func osloValuesEqual(a string, b string) bool {
+	return a == b || normalizeOsloValue(a) == normalizeOsloValue(b)
+}
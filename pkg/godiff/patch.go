@@ -0,0 +1,320 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Patch formats which can be generated from a structured comparison.
+const (
+	JSONPatch  = "json-patch"  // RFC 6902
+	MergePatch = "merge-patch" // RFC 7386
+)
+
+type patchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+func (o patchOperation) MarshalJSON() ([]byte, error) {
+	// A null value is meaningful for add and replace, only remove has none
+	if o.Op == "remove" {
+		return marshalJSON(jsonObject{{Key: "op", Value: o.Op}, {Key: "path", Value: o.Path}}, "")
+	}
+	return marshalJSON(jsonObject{{Key: "op", Value: o.Op}, {Key: "path", Value: o.Path}, {Key: "value", Value: o.Value}}, "")
+}
+
+// jsonObject marshals a MapSlice as a JSON object keeping the key order.
+type jsonObject yaml.MapSlice
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(fmt.Sprint(item.Key), "")
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(toJSONValue(item.Value), "")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalJSON(value interface{}, indent string) ([]byte, error) {
+	// Keep the & < > of values such as URLs as is, json.Marshal escapes them
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func toJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		return jsonObject(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = toJSONValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+func escapePointer(token string) string {
+	// RFC 6901: "~" is escaped first so "/" does not turn into "~01"
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func jsonPatch(org, dest interface{}, pointer string) []patchOperation {
+	/*
+		Build the RFC 6902 operations turning org into dest. Lists which
+		changed length are replaced as a whole, removing items one by one
+		would shift the indexes of the following operations.
+	*/
+	if typeName(org) != typeName(dest) {
+		return []patchOperation{{Op: "replace", Path: pointer, Value: toJSONValue(dest)}}
+	}
+
+	var ops []patchOperation
+	switch org := org.(type) {
+	case yaml.MapSlice:
+		dest := dest.(yaml.MapSlice)
+		destIndex := make(map[string]int, len(dest))
+		for i, item := range dest {
+			destIndex[fmt.Sprint(item.Key)] = i
+		}
+		orgKeys := make(map[string]bool, len(org))
+		for _, item := range org {
+			key := fmt.Sprint(item.Key)
+			orgKeys[key] = true
+			itemPointer := pointer + "/" + escapePointer(key)
+			if i, ok := destIndex[key]; ok {
+				ops = append(ops, jsonPatch(item.Value, dest[i].Value, itemPointer)...)
+			} else {
+				ops = append(ops, patchOperation{Op: "remove", Path: itemPointer})
+			}
+		}
+		for _, item := range dest {
+			key := fmt.Sprint(item.Key)
+			if !orgKeys[key] {
+				ops = append(ops, patchOperation{
+					Op: "add", Path: pointer + "/" + escapePointer(key), Value: toJSONValue(item.Value)})
+			}
+		}
+	case []interface{}:
+		dest := dest.([]interface{})
+		if len(org) != len(dest) {
+			return []patchOperation{{Op: "replace", Path: pointer, Value: toJSONValue(dest)}}
+		}
+		for i := range org {
+			ops = append(ops, jsonPatch(org[i], dest[i], pointer+"/"+strconv.Itoa(i))...)
+		}
+	default:
+		if len(compareTree(org, dest, "")) > 0 {
+			ops = append(ops, patchOperation{Op: "replace", Path: pointer, Value: toJSONValue(dest)})
+		}
+	}
+	return ops
+}
+
+func mergePatch(org, dest interface{}) interface{} {
+	/*
+		Build the RFC 7386 merge patch turning org into dest. Lists can only
+		be replaced and null values in dest cannot be expressed, since null
+		means removal in a merge patch.
+	*/
+	orgMap, orgIsMap := org.(yaml.MapSlice)
+	destMap, destIsMap := dest.(yaml.MapSlice)
+	if !orgIsMap || !destIsMap {
+		return dest
+	}
+	patch := yaml.MapSlice{}
+	destIndex := make(map[string]int, len(destMap))
+	for i, item := range destMap {
+		destIndex[fmt.Sprint(item.Key)] = i
+	}
+	orgIndex := make(map[string]int, len(orgMap))
+	for i, item := range orgMap {
+		key := fmt.Sprint(item.Key)
+		orgIndex[key] = i
+		if _, ok := destIndex[key]; !ok {
+			patch = append(patch, yaml.MapItem{Key: item.Key, Value: nil})
+		}
+	}
+	for _, item := range destMap {
+		i, ok := orgIndex[fmt.Sprint(item.Key)]
+		if !ok {
+			patch = append(patch, yaml.MapItem{Key: item.Key, Value: item.Value})
+		} else if len(compareTree(orgMap[i].Value, item.Value, "")) > 0 {
+			patch = append(patch, yaml.MapItem{Key: item.Key, Value: mergePatch(orgMap[i].Value, item.Value)})
+		}
+	}
+	return patch
+}
+
+func (r *Redactor) stripSecrets(name string, value interface{}, pointer string, secrets map[string]interface{}) (interface{}, bool) {
+	/*
+		Return value without its secrets, which are added to secrets by
+		JSON pointer, and false when value is a secret as a whole.
+	*/
+	if value == nil {
+		return nil, true
+	}
+	if r.secretKey(name) {
+		secrets[pointer] = value
+		return nil, false
+	}
+	switch v := value.(type) {
+	case string:
+		if r.redactString(v) != v {
+			secrets[pointer] = v
+			return nil, false
+		}
+	case yaml.MapSlice:
+		stripped := yaml.MapSlice{}
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if value, ok := r.stripSecrets(key, item.Value, pointer+"/"+escapePointer(key), secrets); ok {
+				stripped = append(stripped, yaml.MapItem{Key: item.Key, Value: value})
+			}
+		}
+		return stripped, true
+	case []interface{}:
+		// Leaving an item out would shift the following ones, a list
+		// holding a secret item is left out as a whole
+		stripped := make([]interface{}, len(v))
+		itemSecrets := map[string]interface{}{}
+		for i, item := range v {
+			value, ok := r.stripSecrets("", item, pointer+"/"+strconv.Itoa(i), itemSecrets)
+			if !ok {
+				secrets[pointer] = v
+				return nil, false
+			}
+			stripped[i] = value
+		}
+		for itemPointer, secret := range itemSecrets {
+			secrets[itemPointer] = secret
+		}
+		return stripped, true
+	}
+	return value, true
+}
+
+func (r *Redactor) patchData(path string, orgData, destData interface{}) (interface{}, interface{}, []string) {
+	/*
+		Leave the secrets out of the compared documents, a masked value
+		written by a patch would overwrite the real one. Return the
+		pointers of the secrets which differ, sorted.
+	*/
+	name := ""
+	if r.secretFile(path) {
+		name = "secret"
+	}
+	orgSecrets, destSecrets := map[string]interface{}{}, map[string]interface{}{}
+	orgData, orgOk := r.stripSecrets(name, orgData, "", orgSecrets)
+	destData, destOk := r.stripSecrets(name, destData, "", destSecrets)
+	if !orgOk || !destOk {
+		orgData, destData = yaml.MapSlice{}, yaml.MapSlice{}
+	}
+	var paths []string
+	for pointer, org := range orgSecrets {
+		if dest, ok := destSecrets[pointer]; !ok || len(compareTree(org, dest, "")) > 0 {
+			paths = append(paths, pointer)
+		}
+	}
+	for pointer := range destSecrets {
+		if _, ok := orgSecrets[pointer]; !ok {
+			paths = append(paths, pointer)
+		}
+	}
+	sort.Strings(paths)
+	return orgData, destData, paths
+}
+
+// Patch returns the difference found by the last JSON or YAML comparison
+// as a JSON Patch or a JSON Merge Patch document. Unless the redaction is
+// disabled, the secrets are left out of the patch and the pointers of
+// those which differ are kept in SecretPaths.
+func (f *CompareFileNames) Patch(format string) ([]byte, error) {
+	if f.orgData == nil && f.destData == nil {
+		return nil, errors.New("No structured data to build a patch for: '" + f.Origin + "'")
+	}
+	orgData, destData := f.orgData, f.destData
+	f.SecretPaths = nil
+	if r := currentRedactor(); r != nil {
+		orgData, destData, f.SecretPaths = r.patchData(f.Origin, orgData, destData)
+	}
+	var patch interface{}
+	switch format {
+	case JSONPatch:
+		ops := jsonPatch(orgData, destData, "")
+		if ops == nil {
+			ops = []patchOperation{}
+		}
+		patch = ops
+	case MergePatch:
+		patch = toJSONValue(mergePatch(orgData, destData))
+	default:
+		return nil, fmt.Errorf("Unknown patch format: %s, expected %s or %s", format, JSONPatch, MergePatch)
+	}
+	return marshalJSON(patch, "  ")
+}
+
+// GeneratePatch compares the origin and destination files and returns the
+// patch turning the origin into the destination. Only comparators building
+// a structured document, such as JSON and YAML, support patches.
+func (f *CompareFileNames) GeneratePatch(format string) ([]byte, error) {
+	orgContent, err := ioutil.ReadFile(f.Origin)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + f.Origin + "'. " + err.Error())
+	}
+	destContent, err := ioutil.ReadFile(f.Destination)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + f.Destination + "'. " + err.Error())
+	}
+	if err := f.compareContents(orgContent, destContent); err != nil {
+		return nil, err
+	}
+	return f.Patch(format)
+}
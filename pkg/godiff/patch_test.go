@@ -0,0 +1,162 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatchSecrets(t *testing.T) {
+	org := "db:\n  password: old\n  url: http://db/?a=1&b=2\nhosts: [rabbit://u:p@h1, h2]\ntoken: same\n"
+	dest := "db:\n  password: new\n  url: http://db/?a=1&b=3\nhosts: [rabbit://u:q@h1]\ntoken: same\n"
+	tests := []struct {
+		name    string
+		format  string
+		redact  bool
+		patch   string
+		secrets []string
+	}{
+		{
+			name:    "json patch",
+			format:  JSONPatch,
+			redact:  true,
+			patch:   `[{"op":"replace","path":"/db/url","value":"http://db/?a=1&b=3"}]`,
+			secrets: []string{"/db/password", "/hosts"},
+		},
+		{
+			name:    "merge patch",
+			format:  MergePatch,
+			redact:  true,
+			patch:   `{"db":{"url":"http://db/?a=1&b=3"}}`,
+			secrets: []string{"/db/password", "/hosts"},
+		},
+		{
+			name:   "show secrets",
+			format: MergePatch,
+			patch:  `{"db":{"password":"new","url":"http://db/?a=1&b=3"},"hosts":["rabbit://u:q@h1"]}`,
+		},
+	}
+	defer SetRedactor(currentRedactor())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			redactor, err := NewRedactor(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !test.redact {
+				redactor = nil
+			}
+			SetRedactor(redactor)
+			f := CompareFileNames{Origin: "org.yaml", Destination: "dest.yaml"}
+			if err := f.compareContents([]byte(org), []byte(dest)); err != nil {
+				t.Fatal(err)
+			}
+			patch, err := f.Patch(test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(strings.Fields(string(patch)), "")
+			if got != test.patch {
+				t.Errorf("Patch() = %s, want %s", got, test.patch)
+			}
+			if !reflect.DeepEqual(f.SecretPaths, test.secrets) {
+				t.Errorf("SecretPaths = %q, want %q", f.SecretPaths, test.secrets)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want string
+	}{
+		{name: "no change", org: `{"a": 1}`, dest: `{"a": 1}`, want: `null`},
+		{name: "replace", org: `{"a": {"b": 1}}`, dest: `{"a": {"b": 2}}`, want: `[{"op":"replace","path":"/a/b","value":2}]`},
+		{name: "add and remove", org: `{"a": 1}`, dest: `{"b": 2}`, want: `[{"op":"remove","path":"/a"},{"op":"add","path":"/b","value":2}]`},
+		{name: "escaped pointer", org: `{"a/b": 1, "c~d": 1}`, dest: `{"a/b": 2, "c~d": 2}`,
+			want: `[{"op":"replace","path":"/a~1b","value":2},{"op":"replace","path":"/c~0d","value":2}]`},
+		{name: "tilde before slash", org: `{"~1": 1}`, dest: `{"~1": 2}`, want: `[{"op":"replace","path":"/~01","value":2}]`},
+		{name: "null value", org: `{"a": 1}`, dest: `{"a": null}`, want: `[{"op":"replace","path":"/a","value":null}]`},
+		{name: "null added", org: `{}`, dest: `{"a": null}`, want: `[{"op":"add","path":"/a","value":null}]`},
+		{name: "list item", org: `{"a": [1, 2]}`, dest: `{"a": [1, 3]}`, want: `[{"op":"replace","path":"/a/1","value":3}]`},
+		{name: "list grown", org: `{"a": [1]}`, dest: `{"a": [1, 2]}`, want: `[{"op":"replace","path":"/a","value":[1,2]}]`},
+		{name: "list shrunk", org: `{"a": [1, 2]}`, dest: `{"a": []}`, want: `[{"op":"replace","path":"/a","value":[]}]`},
+		{name: "type changed", org: `{"a": {"b": 1}}`, dest: `{"a": [1]}`, want: `[{"op":"replace","path":"/a","value":[1]}]`},
+		{name: "whole document", org: `[1]`, dest: `{"a": 1}`, want: `[{"op":"replace","path":"","value":{"a":1}}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			org, err := parseJson([]byte(test.org))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dest, err := parseJson([]byte(test.dest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			patch, err := marshalJSON(jsonPatch(org, dest, ""), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(patch) != test.want {
+				t.Errorf("jsonPatch() = %s, want %s", patch, test.want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want string
+	}{
+		{name: "no change", org: `{"a": 1}`, dest: `{"a": 1}`, want: `{}`},
+		{name: "nested change", org: `{"a": {"b": 1, "c": 1}}`, dest: `{"a": {"b": 2, "c": 1}}`, want: `{"a":{"b":2}}`},
+		{name: "removed key", org: `{"a": 1, "b": 1}`, dest: `{"b": 1}`, want: `{"a":null}`},
+		{name: "added key", org: `{}`, dest: `{"a": {"b": 1}}`, want: `{"a":{"b":1}}`},
+		{name: "list replaced", org: `{"a": [1, 2]}`, dest: `{"a": [1]}`, want: `{"a":[1]}`},
+		{name: "slash in key", org: `{"a/b": 1}`, dest: `{"a/b": 2}`, want: `{"a/b":2}`},
+		{name: "html characters", org: `{"url": "a"}`, dest: `{"url": "http://h/?a=1&b=<2>"}`, want: `{"url":"http://h/?a=1&b=<2>"}`},
+		{name: "not an object", org: `{"a": 1}`, dest: `[1]`, want: `[1]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			org, err := parseJson([]byte(test.org))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dest, err := parseJson([]byte(test.dest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			patch, err := marshalJSON(toJSONValue(mergePatch(org, dest)), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(patch) != test.want {
+				t.Errorf("mergePatch() = %s, want %s", patch, test.want)
+			}
+		})
+	}
+}
@@ -17,13 +17,18 @@
 package godiff
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -31,19 +36,57 @@ import (
 var log = logrus.New()
 
 type GoDiffDataStruct struct {
-	Origin          string
-	Destination     string
-	missingInOrg    []string
-	missingPath     []string
-	missingInDest   []string
-	wrongTypeInOrg  []string
-	wrongTypeInDest []string
-	unmatchFile     []string
+	Origin      string
+	Destination string
+	PatchFormat string
+	MergeKeys   map[string]string
+	// Format of the report printed by ProcessDirectories: text, json or yaml
+	Format string
+	// Directory receiving the diff, patch and report files, in a tree
+	// mirroring the compared directories
+	OutputDir string
+	// Write a single patch for all the files instead of a diff per file
+	CombinedPatch bool
+	// Rules suppressing expected differences
+	IgnoreRules []IgnoreRule
+	// Translation applied to the origin files before comparing
+	Mapping *Mapping
+	// Option definitions of the INI files, in the destination and the
+	// origin releases
+	Metadata       *Metadata
+	OriginMetadata *Metadata
+	// Base directory of a three-way comparison
+	Base string
+	// Number of files compared at once, one when not set
+	Jobs int
+	// Rules ranking the differences, and the severity below which they
+	// are left out of the report
+	SeverityRules []SeverityRule
+	MinSeverity   string
+	Report        Report
+	patch         []string
 }
 
+// Names of the files written in OutputDir.
+const (
+	CombinedPatchFile = "os-diff.patch"
+	SummaryFile       = "summary.txt"
+)
+
+var reportFiles = map[string]string{
+	TextFormat:  SummaryFile,
+	JsonFormat:  "report.json",
+	YamlFormat:  "report.yaml",
+	HtmlFormat:  "report.html",
+	JunitFormat: "junit.xml",
+}
+
+var logFile *os.File
+
 func init() {
 	file, err := os.OpenFile("results.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err == nil {
+		logFile = file
 		log.Formatter = &logrus.TextFormatter{
 			FullTimestamp:          false,
 			DisableTimestamp:       true,
@@ -60,6 +103,15 @@ func init() {
 	}
 }
 
+// SetLogOutput sends the log to w, and to the results.log file when it
+// could be opened.
+func SetLogOutput(w io.Writer) {
+	if logFile != nil {
+		w = io.MultiWriter(w, logFile)
+	}
+	log.SetOutput(w)
+}
+
 func filesEqual(file1, file2 string) (bool, error) {
 	/*
 		Compare hashes of file1 and file2 and return a boolean:
@@ -145,122 +197,375 @@ func checkFile(path1 string, path2 string) (bool, error) {
 		n2, err2 := file2.Read(buf2)
 		if err1 != nil || err2 != nil || n1 != n2 {
 			return false, nil
-			break
 		}
 		if n1 == 0 {
 			break
 		}
 		if string(buf1[:n1]) != string(buf2[:n2]) {
 			return false, nil
-			break
 		}
 	}
 	return true, nil
 }
 
+func sameFile(path1 string, path2 string) bool {
+	stat1, err := os.Stat(path1)
+	if err != nil {
+		return false
+	}
+	stat2, err := os.Stat(path2)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(stat1, stat2)
+}
+
+func (p *GoDiffDataStruct) addFile(file FileReport, service string) {
+	// A file is as severe as its most severe change
+	if len(file.Changes) > 0 {
+		for _, change := range file.Changes {
+			file.Severity = maxSeverity(file.Severity, change.Severity)
+		}
+	} else {
+		file.Severity = fileSeverity(p.SeverityRules, service, file.Origin)
+	}
+	if p.MinSeverity != "" && !severityAtLeast(file.Severity, p.MinSeverity) {
+		log.Info("Difference below the minimum severity (", file.Severity, ") for: ", file.Origin)
+		p.Report.Summary.Filtered++
+		return
+	}
+	p.Report.add(file)
+}
+
+func (p *GoDiffDataStruct) ignorePath(file FileReport, reason string) (func() error, error) {
+	// Count the difference between the paths of file as ignored, if any
+	if file.Status == StatusModified {
+		equal, err := filesEqual(file.Origin, file.Destination)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			file.Status = StatusIdentical
+			return func() error {
+				p.Report.identical(file)
+				return nil
+			}, nil
+		}
+	}
+	log.Info("Difference ignored (", reason, ") for: ", file.Origin)
+	file.IgnoreReason = reason
+	return func() error {
+		p.Report.ignore(file)
+		return nil
+	}, nil
+}
+
+// fileComparison is the comparison of a file with its destination, run by
+// a worker of Process.
+type fileComparison struct {
+	compare CompareFileNames
+	relPath string
+	equal   bool
+	report  []string
+	err     error
+}
+
+func (c *fileComparison) run() {
+	c.equal, c.err = filesEqual(c.compare.Origin, c.compare.Destination)
+	if c.err == nil && c.equal {
+		c.err = c.compare.CheckOptions()
+	}
+	if c.err != nil || c.equal {
+		return
+	}
+	c.report, c.err = c.compare.CompareFiles()
+}
+
+func (p *GoDiffDataStruct) addComparison(c *fileComparison) error {
+	if c.err != nil {
+		return c.err
+	}
+	compareFiles := &c.compare
+	if c.equal {
+		p.Report.identical(FileReport{
+			Origin:      compareFiles.Origin,
+			Destination: compareFiles.Destination,
+			Status:      StatusIdentical,
+			Notes:       compareFiles.Notes,
+		})
+		return nil
+	}
+	p.Report.Summary.Filtered += compareFiles.Filtered
+	file := FileReport{
+		Origin:      compareFiles.Origin,
+		Destination: compareFiles.Destination,
+		Status:      StatusModified,
+		Type:        compareFiles.FileType,
+		Changes:     compareFiles.Changes,
+		Ignored:     compareFiles.Ignored,
+		CarriedOver: compareFiles.CarriedOver,
+		Notes:       compareFiles.Notes,
+	}
+	if len(c.report) == 0 {
+		log.Info("No relevant difference between: ", file.Origin, " and: ", file.Destination)
+		if len(file.Ignored) > 0 {
+			p.Report.ignore(file)
+		}
+		if len(file.Notes) > 0 {
+			file.Status, file.Ignored = StatusEquivalent, nil
+			p.Report.equivalent(file)
+		}
+		return nil
+	}
+	if len(file.Changes) == 0 {
+		file.Diff = strings.Join(c.report, "")
+	}
+	if p.CombinedPatch {
+		rel := filepath.ToSlash(c.relPath)
+		p.patch = append(p.patch, compareFiles.unifiedPatch("a/"+rel, "b/"+rel)...)
+	}
+	p.addFile(file, compareFiles.Service)
+	return nil
+}
+
+func stat(path string) (os.FileInfo, error) {
+	// A missing path is no error, its info is nil
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error in: %s, %s", path, err)
+	}
+	return info, nil
+}
+
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+func dirNames(paths ...string) ([]string, error) {
+	// Sorted names of the entries of the directories
+	seen := make(map[string]bool)
+	var names []string
+	for _, path := range paths {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error in: %s, %s", path, err)
+		}
+		for _, entry := range entries {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func (p *GoDiffDataStruct) Process(dir1 string, dir2 string) error {
 	/*
-		Walk through the first directory and compare each files with the second directory:
-		check by file hashes,
-		if different, compare line by line.
-		report and log results.
+		Walk through both directories at once and classify each relative
+		path once: only in the origin, only in the destination, type
+		mismatch, or files found on both sides, identical by hashes or
+		compared by contents.
+		Files are compared by Jobs workers, the results are added to the
+		report in the walk order once they are all done.
 	*/
-	// Walk through DIR 1
-	filepath.Walk(dir1, func(path string, info os.FileInfo, err error) error {
+	var results []func() error
+	jobs := make(chan *fileComparison)
+	var wg sync.WaitGroup
+	workers := p.Jobs
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for comparison := range jobs {
+				comparison.run()
+			}
+		}()
+	}
+	addFile := func(file FileReport, service string) {
+		results = append(results, func() error {
+			p.addFile(file, service)
+			return nil
+		})
+	}
+	var visit func(relPath string) error
+	visit = func(relPath string) error {
+		path, path2 := filepath.Join(dir1, relPath), filepath.Join(dir2, relPath)
+		file1, err := stat(path)
 		if err != nil {
 			return err
 		}
-		// Get the corresponding file in the second directory
-		relPath, _ := filepath.Rel(dir1, path)
-		path2 := filepath.Join(dir2, relPath)
-		file1, err := os.Stat(path)
+		if file1 == nil && relPath == "." {
+			return fmt.Errorf("Error in: %s, no such file or directory", path)
+		}
+		file2, err := stat(path2)
 		if err != nil {
-			fmt.Errorf("Error in: %s, %s", path, err)
+			return err
+		}
+		if p.OutputDir != "" && relPath != "." && (sameFile(path, p.OutputDir) || sameFile(path2, p.OutputDir)) {
+			// Do not compare the files written by a previous run
 			return nil
 		}
-		file2, err := os.Stat(path2)
-		if err != nil {
-			if !stringInSlice(path, p.missingPath) {
-				if file1.IsDir() {
-					log.Info("Directory is missing: ", path, "\n")
-					p.missingPath = append(p.missingPath, path)
-					// Skip this dir if the current path is missing, no need to walk through all subdir
-					return filepath.SkipDir
-				} else {
-					log.Warn("File is missing: ", path, "\n")
-					p.missingPath = append(p.missingPath, path)
-				}
+		service := serviceOf(dir1, path)
+		file := FileReport{Origin: path, Destination: path2}
+		switch {
+		case file2 == nil:
+			file.Status, file.Directory = StatusMissing, file1.IsDir()
+		case file1 == nil:
+			file.Status, file.Directory = StatusOnlyInDestination, file2.IsDir()
+		case file1.IsDir() != file2.IsDir():
+			file.Status, file.Directory = StatusTypeMismatch, file1.IsDir()
+		case !file1.IsDir():
+			file.Status = StatusModified
+		}
+		if reason, ok := ignoredFile(p.IgnoreRules, service, path); ok && file.Status != "" {
+			// Directories on both sides are walked, their files may differ
+			ignore, err := p.ignorePath(file, reason)
+			if ignore != nil {
+				results = append(results, ignore)
 			}
-		} else {
-			if file1.IsDir() && !file2.IsDir() {
-				if !stringInSlice(path, p.wrongTypeInOrg) {
-					log.Warn("File: ", path, "and: ", path2, " have different type (directory vs file)")
-					p.wrongTypeInOrg = append(p.wrongTypeInOrg, path)
-				}
+			return err
+		}
+		switch file.Status {
+		case StatusMissing:
+			if file.Directory {
+				log.Info("Directory is missing: ", path, "\n")
+			} else {
+				log.Warn("File is missing: ", path, "\n")
 			}
-			if !file1.IsDir() && file2.IsDir() {
-				if !stringInSlice(path, p.wrongTypeInDest) {
-					log.Warn("File: ", path, "and: ", path2, " have different type (directory vs file)")
-					p.wrongTypeInDest = append(p.wrongTypeInDest, path2)
-				}
+			addFile(file, service)
+		case StatusOnlyInDestination:
+			log.Warn("Only in destination: ", path2, "\n")
+			addFile(file, service)
+		case StatusTypeMismatch:
+			log.Warn("File: ", path, " and: ", path2, " have different type (directory vs file)")
+			addFile(file, service)
+		case StatusModified:
+			// Compare the two files, named after the origin when they are
+			// the compared roots
+			outputName := relPath
+			if relPath == "." {
+				outputName = filepath.Base(dir1)
 			}
-			if !file1.IsDir() && !file2.IsDir() {
-				check, err := filesEqual(path, path2)
-				if err != nil {
+			comparison := &fileComparison{
+				compare: CompareFileNames{
+					Origin:         path,
+					Destination:    path2,
+					PatchFormat:    p.PatchFormat,
+					MergeKeys:      p.MergeKeys,
+					IgnoreRules:    p.IgnoreRules,
+					Service:        service,
+					Mapping:        p.Mapping,
+					Metadata:       p.Metadata,
+					OriginMetadata: p.OriginMetadata,
+					SeverityRules:  p.SeverityRules,
+					MinSeverity:    p.MinSeverity,
+					skipDiffFile:   p.CombinedPatch,
+				},
+				relPath: outputName,
+			}
+			if p.OutputDir != "" {
+				comparison.compare.OutputPath = filepath.Join(p.OutputDir, outputName)
+			}
+			if p.Base != "" {
+				comparison.compare.Base = filepath.Join(p.Base, relPath)
+			}
+			results = append(results, func() error {
+				return p.addComparison(comparison)
+			})
+			jobs <- comparison
+		default:
+			// Directories on both sides, symbolic links are not followed
+			if relPath != "." && (isSymlink(path) || isSymlink(path2)) {
+				return nil
+			}
+			names, err := dirNames(path, path2)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if err := visit(filepath.Join(relPath, name)); err != nil {
 					return err
 				}
-				if !check {
-					// Compare the two files
-					if !stringInSlice(path, p.unmatchFile) {
-						p.unmatchFile = append(p.unmatchFile, path)
-
-						compareFiles := CompareFileNames{
-							Origin:      path,
-							Destination: path2,
-						}
-						report, err := compareFiles.CompareFiles()
-						if err != nil {
-							return err
-						}
-
-						if report != nil {
-							if !stringInSlice(path, p.unmatchFile) {
-								p.unmatchFile = append(p.unmatchFile, path)
-							}
-							if !stringInSlice(path2, p.unmatchFile) {
-								p.unmatchFile = append(p.unmatchFile, path2)
-							}
-						}
-					}
-
-				}
-
 			}
 		}
 		return nil
-	})
+	}
+	err := visit(".")
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	for _, add := range results {
+		if err := add(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ProcessDirectories compares the origin and destination trees, writes the
+// output files and prints the report. The reverse parameter is deprecated
+// and ignored: the paths only in the destination are always reported.
 func (p *GoDiffDataStruct) ProcessDirectories(reverse bool) error {
 	// Compare origin vs destination
 	log.Info("Start processing: ", p.Origin, " as source and: ", p.Destination, " as destination.")
-	p.Process(p.Origin, p.Destination)
-	if reverse {
-		p.Process(p.Destination, p.Origin)
-	}
-	fmt.Printf("\n**** Report ****\n")
-	if len(p.missingPath) > 0 {
-		fmt.Printf("\n**** Missing files or directories ****\n")
-		fmt.Println(strings.Join(p.missingPath, "\n"))
-	}
-	if len(p.unmatchFile) > 0 {
-		fmt.Printf("\n**** Files with differences ****\n")
-		fmt.Println(strings.Join(p.unmatchFile, "\n"))
-	}
-	if len(p.wrongTypeInOrg) > 0 {
-		fmt.Printf("\n**** Different file type in origin ****\n")
-		fmt.Println(strings.Join(p.wrongTypeInOrg, "\n"))
+	p.Report.Metadata = RunMetadata{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		Base:        p.Base,
+		StartTime:   time.Now().Format(time.RFC3339),
+	}
+	if err := p.Process(p.Origin, p.Destination); err != nil {
+		return err
+	}
+	p.Report.Metadata.EndTime = time.Now().Format(time.RFC3339)
+	if err := p.writeOutput(); err != nil {
+		return err
+	}
+	return p.Report.Write(os.Stdout, p.Format)
+}
+
+func (p *GoDiffDataStruct) writeOutput() error {
+	/*
+		Write the combined patch and the reports in the output directory:
+		the text summary and the JSON report, plus the report in the
+		requested format.
+	*/
+	if p.CombinedPatch {
+		path := filepath.Join(p.OutputDir, CombinedPatchFile)
+		if err := writeReport(p.patch, path); err != nil {
+			return err
+		}
+	}
+	if p.OutputDir == "" {
+		return nil
+	}
+	formats := []string{TextFormat, JsonFormat}
+	if p.Format != "" && p.Format != TextFormat && p.Format != JsonFormat {
+		formats = append(formats, p.Format)
+	}
+	for _, format := range formats {
+		name, ok := reportFiles[format]
+		if !ok {
+			return fmt.Errorf("Unknown report format: %s", format)
+		}
+		var buf bytes.Buffer
+		if err := p.Report.Write(&buf, format); err != nil {
+			return err
+		}
+		if err := writeReport([]string{buf.String()}, filepath.Join(p.OutputDir, name)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
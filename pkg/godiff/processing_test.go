@@ -0,0 +1,181 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	// Names ending with a slash are directories
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func describeFiles(report Report, origin string, destination string) string {
+	var lines []string
+	for _, file := range report.Files {
+		path, _ := filepath.Rel(origin, file.Origin)
+		if file.Status == StatusOnlyInDestination {
+			path, _ = filepath.Rel(destination, file.Destination)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", path, file.Status))
+	}
+	return strings.Join(lines, "; ")
+}
+
+func compareTrees() (map[string]string, map[string]string) {
+	origin := map[string]string{
+		"keystone/keystone.conf": "[DEFAULT]\ndebug = true\n",
+		"keystone/policy.yaml":   "a: 1\n",
+		"nova/nova.conf":         "[DEFAULT]\ndebug = true\n",
+		"nova/api-paste.ini":     "[app]\nuse = egg\n",
+		"glance/":                "",
+		"swift":                  "file\n",
+	}
+	destination := map[string]string{
+		"keystone/keystone.conf": "[DEFAULT]\ndebug = false\n",
+		"keystone/policy.yaml":   "a: 1\n",
+		"nova/nova.conf":         "[DEFAULT]\ndebug = true\n",
+		"cinder/cinder.conf":     "[DEFAULT]\n",
+		"glance/":                "",
+		"swift/":                 "",
+	}
+	return origin, destination
+}
+
+func TestProcessDirectories(t *testing.T) {
+	want := "cinder only_in_destination; keystone/keystone.conf modified; " +
+		"nova/api-paste.ini missing; swift type_mismatch"
+	for _, reverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reverse %v", reverse), func(t *testing.T) {
+			orgFiles, destFiles := compareTrees()
+			origin, destination := writeTree(t, orgFiles), writeTree(t, destFiles)
+			p := GoDiffDataStruct{Origin: origin, Destination: destination, Format: JsonFormat, OutputDir: t.TempDir()}
+			if err := p.ProcessDirectories(reverse); err != nil {
+				t.Fatal(err)
+			}
+			var changed []FileReport
+			for _, file := range p.Report.Files {
+				if file.Status != StatusIdentical {
+					changed = append(changed, file)
+				}
+			}
+			if got := describeFiles(Report{Files: changed}, origin, destination); got != want {
+				t.Errorf("Files = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestProcessJobs(t *testing.T) {
+	orgFiles, destFiles := compareTrees()
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("service%d/service%d.conf", i%4, i)
+		orgFiles[name] = fmt.Sprintf("[DEFAULT]\nworkers = %d\n", i)
+		destFiles[name] = fmt.Sprintf("[DEFAULT]\nworkers = %d\n", i%3)
+	}
+	origin, destination := writeTree(t, orgFiles), writeTree(t, destFiles)
+	var reports []string
+	for _, jobs := range []int{1, 8} {
+		p := GoDiffDataStruct{Origin: origin, Destination: destination, Format: JsonFormat, OutputDir: t.TempDir(), Jobs: jobs}
+		if err := p.ProcessDirectories(false); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, describeFiles(p.Report, origin, destination))
+	}
+	if reports[0] != reports[1] {
+		t.Errorf("Files with 8 jobs = %q, want as with 1 job %q", reports[1], reports[0])
+	}
+}
+
+func listFiles(t *testing.T, root string) string {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return strings.Join(files, " ")
+}
+
+func TestProcessOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		format   string
+		combined bool
+		want     string
+	}{
+		{name: "mirrored tree", want: "keystone/keystone.conf.diff report.json summary.txt"},
+		{name: "single file", file: "keystone/keystone.conf", want: "keystone.conf.diff report.json summary.txt"},
+		{name: "combined patch", combined: true, want: "os-diff.patch report.json summary.txt"},
+		{name: "html report", format: HtmlFormat, want: "keystone/keystone.conf.diff report.html report.json summary.txt"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orgFiles := map[string]string{"keystone/keystone.conf": "[DEFAULT]\ndebug = true\n"}
+			destFiles := map[string]string{"keystone/keystone.conf": "[DEFAULT]\ndebug = false\n"}
+			origin, destination := writeTree(t, orgFiles), writeTree(t, destFiles)
+			before := listFiles(t, origin)
+			if test.file != "" {
+				origin, destination = filepath.Join(origin, test.file), filepath.Join(destination, test.file)
+			}
+			output := t.TempDir()
+			p := GoDiffDataStruct{Origin: origin, Destination: destination, Format: test.format,
+				OutputDir: output, CombinedPatch: test.combined}
+			if err := p.ProcessDirectories(false); err != nil {
+				t.Fatal(err)
+			}
+			if got := listFiles(t, output); got != test.want {
+				t.Errorf("output files = %q, want %q", got, test.want)
+			}
+			if test.file == "" {
+				if got := listFiles(t, origin); got != before {
+					t.Errorf("origin files = %q, want %q untouched", got, before)
+				}
+			}
+		})
+	}
+}
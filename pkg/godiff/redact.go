@@ -0,0 +1,287 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-yaml/yaml"
+	"github.com/sirupsen/logrus"
+)
+
+// Option names holding secrets, matched against the lower case name with
+// dashes read as underscores.
+var DefaultSecretKeys = []string{
+	`(password|passwd|_pass|secret|secret_key|token|credentials?|private_key|apikey)$`,
+	`(^|_)key$`,
+}
+
+// Files holding secrets only, such as keystone fernet keys.
+var DefaultSecretFiles = []string{
+	"fernet-keys/*",
+	"credential-keys/*",
+}
+
+var (
+	urlAuthority = regexp.MustCompile(`[a-zA-Z][\w+.-]*://[^/?#\s]*@[^/?#\s]*`)
+	urlUserinfo  = regexp.MustCompile(`((?:,|://)[^:,@/]*):([^,]*)@`)
+	optionLine   = regexp.MustCompile(`^([+\- ]?\s*)([\w.-]+)(\s*[=:]\s*)(.*)$`)
+	optionInText = regexp.MustCompile(`([\w.-]+)(\s*=\s*)(\S+)`)
+	maskedValue  = regexp.MustCompile(`<redacted:[0-9a-f]{8}>`)
+)
+
+// Redactor masks secret values in the differences and the log. A masked
+// value shows a hash salted for the run, so two secrets can still be told
+// apart without being revealed.
+type Redactor struct {
+	keys  []*regexp.Regexp
+	files []string
+	salt  []byte
+}
+
+var redaction = struct {
+	sync.RWMutex
+	redactor *Redactor
+}{}
+
+func init() {
+	redactor, err := NewRedactor(nil)
+	if err != nil {
+		panic(err)
+	}
+	SetRedactor(redactor)
+	log.AddHook(redactHook{})
+}
+
+// NewRedactor returns a Redactor for the default secret options and files,
+// and the options matching the extra regular expressions.
+func NewRedactor(extraKeys []string) (*Redactor, error) {
+	r := &Redactor{files: DefaultSecretFiles, salt: make([]byte, 16)}
+	if _, err := rand.Read(r.salt); err != nil {
+		return nil, err
+	}
+	for _, pattern := range append(append([]string{}, DefaultSecretKeys...), extraKeys...) {
+		key, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid secret pattern: %s, %s", pattern, err)
+		}
+		r.keys = append(r.keys, key)
+	}
+	return r, nil
+}
+
+// SetRedactor sets the Redactor used for the differences and the log, nil
+// disables the redaction.
+func SetRedactor(r *Redactor) {
+	redaction.Lock()
+	defer redaction.Unlock()
+	redaction.redactor = r
+}
+
+func currentRedactor() *Redactor {
+	redaction.RLock()
+	defer redaction.RUnlock()
+	return redaction.redactor
+}
+
+func (r *Redactor) mask(value string) string {
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(value))
+	return "<redacted:" + hex.EncodeToString(mac.Sum(nil))[:8] + ">"
+}
+
+func (r *Redactor) secretKey(name string) bool {
+	name = canonicalOption(strings.ToLower(name))
+	for _, key := range r.keys {
+		if key.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) secretOption(name string, value string) bool {
+	// Values already masked hold a secret too, such as URL passwords
+	return r.secretKey(name) || maskedValue.MatchString(value)
+}
+
+func (r *Redactor) secretFile(path string) bool {
+	for _, pattern := range r.files {
+		if matchFile(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) redactString(s string) string {
+	// Mask the passwords of the URLs, such as transport_url or connection,
+	// which can have several hosts with their own credentials. A password
+	// runs to the last @ of its host, it can hold an unescaped @.
+	return urlAuthority.ReplaceAllStringFunc(s, func(authority string) string {
+		return urlUserinfo.ReplaceAllStringFunc(authority, func(userinfo string) string {
+			parts := urlUserinfo.FindStringSubmatch(userinfo)
+			return parts[1] + ":" + r.mask(parts[2]) + "@"
+		})
+	})
+}
+
+func (r *Redactor) redactOption(name string, value string) string {
+	if r.secretKey(name) {
+		return r.mask(value)
+	}
+	return r.redactString(value)
+}
+
+func redactOption(name string, value string) string {
+	if r := currentRedactor(); r != nil {
+		return r.redactOption(name, value)
+	}
+	return value
+}
+
+func (r *Redactor) redactValue(name string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if r.secretKey(name) {
+		return r.mask(displayValue(value))
+	}
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case yaml.MapSlice:
+		redacted := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			redacted[i] = yaml.MapItem{Key: item.Key, Value: r.redactValue(fmt.Sprint(item.Key), item.Value)}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactValue("", item)
+		}
+		return redacted
+	}
+	return value
+}
+
+func changeName(change Change) string {
+	// Name of the changed option, or last key of the changed path, list
+	// items are named after their list
+	if change.Key != "" || change.Section != "" {
+		return change.Key
+	}
+	path := change.Path
+	for strings.HasSuffix(path, "]") {
+		if strings.HasSuffix(path, "\"]") {
+			i := strings.LastIndex(path, "[\"")
+			if name, err := strconv.Unquote(path[i+1 : len(path)-1]); i >= 0 && err == nil {
+				return name
+			}
+		}
+		i := strings.LastIndex(path, "[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+func (r *Redactor) redactChanges(changes []Change, path string) []Change {
+	secretFile := r.secretFile(path)
+	redacted := make([]Change, len(changes))
+	for i, change := range changes {
+		if change.Kind != ChangeMoved {
+			name := changeName(change)
+			if secretFile {
+				name = "secret"
+			}
+			if change.Kind == ChangeType && change.Note == "" {
+				// The masks are strings, keep the types in the note
+				change.Note = fmt.Sprintf("type changed from %s to %s", typeName(change.Old), typeName(change.New))
+			}
+			change.Old = r.redactValue(name, change.Old)
+			change.New = r.redactValue(name, change.New)
+		}
+		redacted[i] = change
+	}
+	return redacted
+}
+
+func (r *Redactor) redactLines(lines []string, path string) []string {
+	/*
+		Mask the secrets of a unified diff: every line of a secret file,
+		the values of secret options, and the URL passwords.
+	*/
+	secretFile := r.secretFile(path)
+	redacted := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") ||
+			strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "\\") {
+			redacted[i] = line
+			continue
+		}
+		body := strings.TrimSuffix(line, "\n")
+		eol := line[len(body):]
+		if secretFile && body != "" {
+			redacted[i] = body[:1] + r.mask(body[1:]) + eol
+			continue
+		}
+		if parts := optionLine.FindStringSubmatch(body); parts != nil && r.secretKey(parts[2]) {
+			redacted[i] = parts[1] + parts[2] + parts[3] + r.mask(parts[4]) + eol
+			continue
+		}
+		redacted[i] = r.redactString(line)
+	}
+	return redacted
+}
+
+func (r *Redactor) redactText(text string) string {
+	// Mask the secrets written as key=value or in URLs in a message
+	text = optionInText.ReplaceAllStringFunc(text, func(option string) string {
+		parts := optionInText.FindStringSubmatch(option)
+		if r.secretKey(parts[1]) {
+			return parts[1] + parts[2] + r.mask(parts[3])
+		}
+		return option
+	})
+	return r.redactString(text)
+}
+
+// redactHook masks the secrets left in the log messages.
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	if r := currentRedactor(); r != nil {
+		entry.Message = r.redactText(entry.Message)
+	}
+	return nil
+}
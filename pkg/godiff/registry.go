@@ -0,0 +1,189 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Names of the built-in comparators.
+const (
+	IniType  = "ini"
+	JsonType = "json"
+	YamlType = "yaml"
+	TextType = "text"
+)
+
+// Comparator compares the content of two files of a given format.
+type Comparator interface {
+	// Name of the format handled, used by type overrides.
+	Name() string
+	// Detect returns the confidence, from 0 to 100, that the file at path
+	// holds this format. 0 means the comparator cannot handle the file.
+	Detect(path string, data []byte) int
+	// Compare records the differences between origin and dest in f:
+	// structured changes with f.AddChanges, which applies the ignore rules,
+	// severities and redaction, or a line diff with f.Compare.
+	Compare(f *CompareFileNames, origin []byte, dest []byte) error
+}
+
+type registeredComparator struct {
+	comparator Comparator
+	priority   int
+}
+
+type typeOverride struct {
+	pattern string
+	name    string
+}
+
+var registry = struct {
+	sync.RWMutex
+	comparators []registeredComparator
+	overrides   []typeOverride
+}{}
+
+func init() {
+	RegisterComparator(iniComparator{}, 30)
+	RegisterComparator(jsonComparator{}, 20)
+	RegisterComparator(yamlComparator{}, 10)
+	RegisterComparator(textComparator{}, 0)
+}
+
+// RegisterComparator adds c to the registry, or replaces the comparator with
+// the same name. Comparators with a higher priority are tried first.
+func RegisterComparator(c Comparator, priority int) {
+	registry.Lock()
+	defer registry.Unlock()
+	// Build a new slice, lookups may still hold the previous one
+	comparators := []registeredComparator{{comparator: c, priority: priority}}
+	for _, r := range registry.comparators {
+		if r.comparator.Name() != c.Name() {
+			comparators = append(comparators, r)
+		}
+	}
+	sort.SliceStable(comparators, func(i, j int) bool {
+		return comparators[i].priority > comparators[j].priority
+	})
+	registry.comparators = comparators
+}
+
+// SetTypeOverride forces the files whose name or path match the glob
+// pattern to be compared with the comparator called name, e.g. *.conf=ini.
+func SetTypeOverride(pattern string, name string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return errors.New("Invalid pattern: '" + pattern + "'. " + err.Error())
+	}
+	if LookupComparator(name) == nil {
+		return errors.New("Unknown file type: '" + name + "'")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	overrides := append([]typeOverride{}, registry.overrides...)
+	registry.overrides = append(overrides, typeOverride{pattern: pattern, name: name})
+	return nil
+}
+
+// LookupComparator returns the registered comparator called name, or nil.
+func LookupComparator(name string) Comparator {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, r := range registry.comparators {
+		if r.comparator.Name() == name {
+			return r.comparator
+		}
+	}
+	return nil
+}
+
+func comparatorFor(orgPath string, destPath string, orgContent []byte, destContent []byte) (Comparator, int) {
+	/*
+		Return the comparator forced for the files by an override, or the
+		one with the best confidence for both files, by priority on a tie.
+		The text comparator is used when nothing else matches.
+	*/
+	registry.RLock()
+	overrides, comparators := registry.overrides, registry.comparators
+	registry.RUnlock()
+	for _, o := range overrides {
+		for _, path := range []string{orgPath, destPath} {
+			nameMatch, _ := filepath.Match(o.pattern, filepath.Base(path))
+			pathMatch, _ := filepath.Match(o.pattern, path)
+			if nameMatch || pathMatch {
+				if c := LookupComparator(o.name); c != nil {
+					return c, 100
+				}
+			}
+		}
+	}
+	var best Comparator = textComparator{}
+	bestConfidence := 0
+	for _, r := range comparators {
+		confidence := r.comparator.Detect(orgPath, orgContent)
+		if destConfidence := r.comparator.Detect(destPath, destContent); destConfidence < confidence {
+			confidence = destConfidence
+		}
+		if confidence > bestConfidence {
+			best, bestConfidence = r.comparator, confidence
+		}
+	}
+	return best, bestConfidence
+}
+
+type iniComparator struct{}
+
+func (iniComparator) Name() string { return IniType }
+
+func (iniComparator) Detect(path string, data []byte) int { return detectIni(path, data) }
+
+func (iniComparator) Compare(f *CompareFileNames, origin []byte, dest []byte) error {
+	return f.compareIni(origin, dest, f.Origin, f.Destination)
+}
+
+type jsonComparator struct{}
+
+func (jsonComparator) Name() string { return JsonType }
+
+func (jsonComparator) Detect(path string, data []byte) int { return detectJson(path, data) }
+
+func (jsonComparator) Compare(f *CompareFileNames, origin []byte, dest []byte) error {
+	return f.CompareJsonFiles(origin, dest)
+}
+
+type yamlComparator struct{}
+
+func (yamlComparator) Name() string { return YamlType }
+
+func (yamlComparator) Detect(path string, data []byte) int { return detectYaml(path, data) }
+
+func (yamlComparator) Compare(f *CompareFileNames, origin []byte, dest []byte) error {
+	return f.CompareYamlFiles(origin, dest)
+}
+
+type textComparator struct{}
+
+func (textComparator) Name() string { return TextType }
+
+// Any file can be compared line by line, with the lowest confidence
+func (textComparator) Detect(path string, data []byte) int { return 1 }
+
+func (textComparator) Compare(f *CompareFileNames, origin []byte, dest []byte) error {
+	return f.Compare(origin, dest)
+}
@@ -0,0 +1,135 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"strings"
+	"testing"
+)
+
+// pairsComparator stands for a comparator registered from outside the
+// package, it only uses the exported API.
+type pairsComparator struct{}
+
+func (pairsComparator) Name() string { return "pairs" }
+
+func (pairsComparator) Detect(path string, data []byte) int { return 0 }
+
+func (pairsComparator) Compare(f *CompareFileNames, origin []byte, dest []byte) error {
+	pairs := func(data []byte) map[string]string {
+		values := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			values[parts[0]] = parts[1]
+		}
+		return values
+	}
+	orgPairs, destPairs := pairs(origin), pairs(dest)
+	var changes []Change
+	for _, key := range []string{"password", "host", "port"} {
+		if orgPairs[key] != destPairs[key] {
+			changes = append(changes, Change{Path: key, Kind: ChangeModified, Old: orgPairs[key], New: destPairs[key]})
+		}
+	}
+	f.AddChanges(changes)
+	return nil
+}
+
+func TestRegisteredComparator(t *testing.T) {
+	RegisterComparator(pairsComparator{}, 0)
+	if err := SetTypeOverride("*.pairs", "pairs"); err != nil {
+		t.Fatal(err)
+	}
+	rules := []IgnoreRule{{Path: "port", Reason: "port moved"}}
+	if err := CompileIgnoreRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	f := CompareFileNames{Origin: "org.pairs", Destination: "dest.pairs", IgnoreRules: rules}
+	org := "password=old\nhost=a\nport=1\n"
+	dest := "password=new\nhost=b\nport=2\n"
+	if err := f.compareContents([]byte(org), []byte(dest)); err != nil {
+		t.Fatal(err)
+	}
+	if f.FileType != "pairs" {
+		t.Fatalf("FileType = %q, want pairs", f.FileType)
+	}
+	if got := describeChanges(f.Changes); got != "changed password; changed host" {
+		t.Errorf("Changes = %q", got)
+	}
+	if len(f.Ignored) != 1 || f.Ignored[0].IgnoreReason != "port moved" {
+		t.Errorf("Ignored = %v, want the port change", f.Ignored)
+	}
+	for _, change := range f.Changes {
+		if change.Severity == "" {
+			t.Errorf("change %s has no severity", change.Path)
+		}
+	}
+	if report := strings.Join(f.DiffReport, ""); strings.Contains(report, "old") || strings.Contains(report, "new") {
+		t.Errorf("DiffReport shows the password:\n%s", report)
+	}
+}
+
+func TestComparatorFor(t *testing.T) {
+	if err := SetTypeOverride("*.forced", IniType); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		orgPath     string
+		orgContent  string
+		destPath    string
+		destContent string
+		want        string
+	}{
+		{name: "ini", orgPath: "keystone.conf", orgContent: "[DEFAULT]\ndebug = true\n", want: IniType},
+		{name: "json", orgPath: "data.json", orgContent: `{"a": 1}`, want: JsonType},
+		{name: "json without extension", orgPath: "data", orgContent: `{"a": 1}`, want: JsonType},
+		{name: "yaml", orgPath: "data.yaml", orgContent: "a: 1\n", want: YamlType},
+		{name: "plain text", orgPath: "README", orgContent: "Some text\n", want: TextType},
+		{name: "different types", orgPath: "data.json", orgContent: `{"a": 1}`,
+			destPath: "data.conf", destContent: "debug = true\n", want: TextType},
+		{name: "override", orgPath: "data.forced", orgContent: "Some text\n", want: IniType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.destPath == "" {
+				test.destPath, test.destContent = test.orgPath, test.orgContent
+			}
+			c, _ := comparatorFor(test.orgPath, test.destPath, []byte(test.orgContent), []byte(test.destContent))
+			if c.Name() != test.want {
+				t.Errorf("comparatorFor() = %s, want %s", c.Name(), test.want)
+			}
+		})
+	}
+}
+
+func TestSetTypeOverride(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		valid   bool
+	}{
+		{pattern: "*.cfg", name: IniType, valid: true},
+		{pattern: "*.cfg", name: "toml"},
+		{pattern: "[", name: IniType},
+	}
+	for _, test := range tests {
+		if err := SetTypeOverride(test.pattern, test.name); (err == nil) != test.valid {
+			t.Errorf("SetTypeOverride(%q, %q) = %v, want valid %v", test.pattern, test.name, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Report formats supported by Report.Write.
+const (
+	TextFormat = "text"
+	JsonFormat = "json"
+	YamlFormat = "yaml"
+)
+
+// Status of a compared path, StatusMissing is a path only in the origin.
+const (
+	StatusModified          = "modified"
+	StatusMissing           = "missing"
+	StatusOnlyInDestination = "only_in_destination"
+	StatusTypeMismatch      = "type_mismatch"
+	StatusIdentical         = "identical"
+	StatusEquivalent        = "equivalent"
+)
+
+// Report holds the results of a comparison run.
+type Report struct {
+	Metadata RunMetadata  `json:"metadata" yaml:"metadata"`
+	Summary  Summary      `json:"summary" yaml:"summary"`
+	Files    []FileReport `json:"files" yaml:"files"`
+	// Files whose differences are all suppressed by ignore rules, and the
+	// suppressed changes of Files, only listed when ShowIgnored is set
+	Ignored     []FileReport `json:"ignored,omitempty" yaml:"ignored,omitempty"`
+	ShowIgnored bool         `json:"-" yaml:"-"`
+	// Files found the same on both sides, and the files only differing by
+	// their notes, such as options spelled differently
+	Identical  []FileReport `json:"identical,omitempty" yaml:"identical,omitempty"`
+	Equivalent []FileReport `json:"equivalent,omitempty" yaml:"equivalent,omitempty"`
+}
+
+// RunMetadata describes a comparison run, times are in RFC 3339 format.
+type RunMetadata struct {
+	Origin      string `json:"origin" yaml:"origin"`
+	Destination string `json:"destination" yaml:"destination"`
+	Base        string `json:"base,omitempty" yaml:"base,omitempty"`
+	StartTime   string `json:"start_time" yaml:"start_time"`
+	EndTime     string `json:"end_time" yaml:"end_time"`
+}
+
+// Summary counts the files and changes of a report by status.
+type Summary struct {
+	Identical         int `json:"identical" yaml:"identical"`
+	Equivalent        int `json:"equivalent" yaml:"equivalent"`
+	Modified          int `json:"modified" yaml:"modified"`
+	Missing           int `json:"missing" yaml:"missing"`
+	OnlyInDestination int `json:"only_in_destination" yaml:"only_in_destination"`
+	TypeMismatch      int `json:"type_mismatch" yaml:"type_mismatch"`
+	Changes           int `json:"changes" yaml:"changes"`
+	// Files and changes suppressed by ignore rules
+	Ignored int `json:"ignored" yaml:"ignored"`
+	// Files by severity, and differences below the minimum severity
+	Critical int `json:"critical" yaml:"critical"`
+	Warning  int `json:"warning" yaml:"warning"`
+	Info     int `json:"info" yaml:"info"`
+	Filtered int `json:"filtered" yaml:"filtered"`
+	// Changes by three-way class, against the base
+	CarriedOver     int `json:"carried_over,omitempty" yaml:"carried_over,omitempty"`
+	Lost            int `json:"lost,omitempty" yaml:"lost,omitempty"`
+	DestinationOnly int `json:"destination_only,omitempty" yaml:"destination_only,omitempty"`
+	Conflict        int `json:"conflict,omitempty" yaml:"conflict,omitempty"`
+}
+
+// FileReport is the result for one path found in the origin or the
+// destination. Changes hold
+// the structured differences, Diff the text diff when there are none.
+type FileReport struct {
+	Origin      string   `json:"origin" yaml:"origin"`
+	Destination string   `json:"destination" yaml:"destination"`
+	Status      string   `json:"status" yaml:"status"`
+	Severity    string   `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Directory   bool     `json:"directory,omitempty" yaml:"directory,omitempty"`
+	Type        string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Changes     []Change `json:"changes,omitempty" yaml:"changes,omitempty"`
+	Diff        string   `json:"diff,omitempty" yaml:"diff,omitempty"`
+	// Suppressed changes, or the reason the whole file is suppressed
+	Ignored      []Change `json:"ignored,omitempty" yaml:"ignored,omitempty"`
+	IgnoreReason string   `json:"ignore_reason,omitempty" yaml:"ignore_reason,omitempty"`
+	// Remarks which are not differences
+	Notes []string `json:"notes,omitempty" yaml:"notes,omitempty"`
+	// Customizations of the origin from the base kept in the destination
+	CarriedOver []Change `json:"carried_over,omitempty" yaml:"carried_over,omitempty"`
+}
+
+type changeDocument struct {
+	Path     string      `json:"path" yaml:"path"`
+	Section  string      `json:"section,omitempty" yaml:"section,omitempty"`
+	Key      string      `json:"key,omitempty" yaml:"key,omitempty"`
+	Kind     string      `json:"kind" yaml:"kind"`
+	Old      interface{} `json:"old,omitempty" yaml:"old,omitempty"`
+	New      interface{} `json:"new,omitempty" yaml:"new,omitempty"`
+	Note     string      `json:"note,omitempty" yaml:"note,omitempty"`
+	Ignored  string      `json:"ignore_reason,omitempty" yaml:"ignore_reason,omitempty"`
+	Severity string      `json:"severity,omitempty" yaml:"severity,omitempty"`
+	ThreeWay string      `json:"three_way,omitempty" yaml:"three_way,omitempty"`
+}
+
+func (c Change) document(value func(interface{}) interface{}) changeDocument {
+	return changeDocument{
+		Path:     c.Path,
+		Section:  c.Section,
+		Key:      c.Key,
+		Kind:     c.Kind,
+		Old:      value(c.Old),
+		New:      value(c.New),
+		Note:     c.Note,
+		Ignored:  c.IgnoreReason,
+		Severity: c.Severity,
+		ThreeWay: c.ThreeWay,
+	}
+}
+
+func (c Change) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.document(toJSONValue))
+}
+
+func (c Change) MarshalYAML() (interface{}, error) {
+	return c.document(toYAMLValue), nil
+}
+
+func toYAMLValue(value interface{}) interface{} {
+	// JSON numbers would be written as strings
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case yaml.MapSlice:
+		ordered := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			ordered[i] = yaml.MapItem{Key: item.Key, Value: toYAMLValue(item.Value)}
+		}
+		return ordered
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = toYAMLValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+func (r *Report) add(file FileReport) {
+	switch file.Status {
+	case StatusModified:
+		r.Summary.Modified++
+	case StatusMissing:
+		r.Summary.Missing++
+	case StatusOnlyInDestination:
+		r.Summary.OnlyInDestination++
+	case StatusTypeMismatch:
+		r.Summary.TypeMismatch++
+	}
+	switch file.Severity {
+	case SeverityCritical:
+		r.Summary.Critical++
+	case SeverityWarning:
+		r.Summary.Warning++
+	case SeverityInfo:
+		r.Summary.Info++
+	}
+	for _, change := range file.Changes {
+		switch change.ThreeWay {
+		case ThreeWayLost:
+			r.Summary.Lost++
+		case ThreeWayDestinationOnly:
+			r.Summary.DestinationOnly++
+		case ThreeWayConflict:
+			r.Summary.Conflict++
+		}
+	}
+	r.Summary.CarriedOver += len(file.CarriedOver)
+	r.Summary.Changes += len(file.Changes)
+	r.Summary.Ignored += len(file.Ignored)
+	if !r.ShowIgnored {
+		file.Ignored = nil
+	}
+	r.Files = append(r.Files, file)
+}
+
+func (r *Report) ignore(file FileReport) {
+	// Count a suppressed file, or its suppressed changes
+	if file.IgnoreReason != "" {
+		r.Summary.Ignored++
+	} else {
+		r.Summary.Ignored += len(file.Ignored)
+	}
+	if r.ShowIgnored {
+		r.Ignored = append(r.Ignored, file)
+	}
+}
+
+func (r *Report) identical(file FileReport) {
+	r.Summary.Identical++
+	r.Identical = append(r.Identical, file)
+}
+
+func (r *Report) equivalent(file FileReport) {
+	r.Summary.Equivalent++
+	r.Equivalent = append(r.Equivalent, file)
+}
+
+// HasDifferences tells whether any difference was found.
+func (r *Report) HasDifferences() bool {
+	return len(r.Files) > 0
+}
+
+// Thresholds deciding which differences fail a run, with the severities.
+const (
+	FailOnAny     = "any"
+	FailOnMissing = "missing"
+	FailOnNever   = "never"
+)
+
+// ValidateFailOn checks failOn is a known threshold or severity.
+func ValidateFailOn(failOn string) error {
+	switch failOn {
+	case FailOnAny, FailOnMissing, FailOnNever:
+		return nil
+	}
+	if _, ok := severityRanks[failOn]; ok {
+		return nil
+	}
+	return fmt.Errorf("Unknown fail-on threshold: %s, expected any, missing, never, info, warning or critical", failOn)
+}
+
+// ValidateFormat checks format is a known report format.
+func ValidateFormat(format string) error {
+	switch format {
+	case TextFormat, JsonFormat, YamlFormat, HtmlFormat, JunitFormat:
+		return nil
+	}
+	return fmt.Errorf("Unknown report format: %s, expected text, json, yaml, html or junit", format)
+}
+
+// Fails tells whether the report holds differences reaching failOn: any
+// difference, only missing files or directories, never, or the files
+// with differences of at least the given severity.
+func (r *Report) Fails(failOn string) bool {
+	switch failOn {
+	case FailOnNever:
+		return false
+	case FailOnMissing:
+		return r.Summary.Missing > 0
+	case FailOnAny:
+		return r.HasDifferences()
+	}
+	for _, file := range r.Files {
+		if severityAtLeast(file.Severity, failOn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write serializes the report to w in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "", TextFormat:
+		r.writeText(w)
+		return nil
+	case JsonFormat:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case YamlFormat:
+		data, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case HtmlFormat:
+		return r.writeHtml(w)
+	case JunitFormat:
+		return r.writeJunit(w)
+	}
+	return fmt.Errorf("Unknown report format: %s", format)
+}
+
+func (r *Report) filesWith(status string) []FileReport {
+	var files []FileReport
+	for _, file := range r.Files {
+		if file.Status == status {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *Report) withNotes() []FileReport {
+	var files []FileReport
+	for _, list := range [][]FileReport{r.Files, r.Equivalent, r.Identical} {
+		for _, file := range list {
+			if len(file.Notes) > 0 {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+func (r *Report) writeText(w io.Writer) {
+	fmt.Fprintf(w, "\n**** Report ****\n")
+	if files := r.filesWith(StatusMissing); len(files) > 0 {
+		fmt.Fprintf(w, "\n**** Missing files or directories ****\n")
+		for _, file := range files {
+			fmt.Fprintf(w, "%s (%s)\n", file.Origin, file.Severity)
+		}
+	}
+	if files := r.filesWith(StatusOnlyInDestination); len(files) > 0 {
+		fmt.Fprintf(w, "\n**** Only in destination ****\n")
+		for _, file := range files {
+			fmt.Fprintf(w, "%s (%s)\n", file.Destination, file.Severity)
+		}
+	}
+	files := r.filesWith(StatusModified)
+	if len(files) > 0 {
+		fmt.Fprintf(w, "\n**** Files with differences ****\n")
+		for _, file := range files {
+			fmt.Fprintf(w, "%s (%s, %s)\n%s\n", file.Origin, file.Type, file.Severity, file.Destination)
+		}
+	}
+	structured := false
+	for _, file := range files {
+		if len(file.Changes) > 0 {
+			if !structured {
+				fmt.Fprintf(w, "\n**** Structured differences ****\n")
+				structured = true
+			}
+			fmt.Fprintf(w, "%s: %s (%s)", file.Origin, summarizeChanges(file.Changes), summarizeSeverities(file.Changes))
+			if r.Metadata.Base != "" {
+				fmt.Fprintf(w, " [%s]", summarizeThreeWay(file))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if files := r.filesWith(StatusTypeMismatch); len(files) > 0 {
+		fmt.Fprintf(w, "\n**** Different file type (directory vs file) ****\n")
+		for _, file := range files {
+			fmt.Fprintf(w, "%s and %s (%s)\n", file.Origin, file.Destination, file.Severity)
+		}
+	}
+	notes := false
+	for _, file := range r.withNotes() {
+		if !notes {
+			fmt.Fprintf(w, "\n**** Notes ****\n")
+			notes = true
+		}
+		for _, note := range file.Notes {
+			fmt.Fprintf(w, "%s: %s\n", file.Origin, note)
+		}
+	}
+	fmt.Fprintf(w, "\n%d identical, %d equivalent, %d modified, %d only in origin, %d only in destination, %d type mismatch\n",
+		r.Summary.Identical, r.Summary.Equivalent, r.Summary.Modified, r.Summary.Missing, r.Summary.OnlyInDestination, r.Summary.TypeMismatch)
+	if r.HasDifferences() {
+		fmt.Fprintf(w, "%d critical, %d warning, %d info\n", r.Summary.Critical, r.Summary.Warning, r.Summary.Info)
+	}
+	if r.Metadata.Base != "" {
+		fmt.Fprintf(w, "%d carried over, %d lost, %d changed only in destination, %d in conflict\n",
+			r.Summary.CarriedOver, r.Summary.Lost, r.Summary.DestinationOnly, r.Summary.Conflict)
+	}
+	if r.Summary.Filtered > 0 {
+		fmt.Fprintf(w, "%d differences below the minimum severity\n", r.Summary.Filtered)
+	}
+	if r.Summary.Ignored > 0 && !r.ShowIgnored {
+		fmt.Fprintf(w, "\n%d differences ignored, list them with --show-ignored\n", r.Summary.Ignored)
+	}
+	if r.ShowIgnored && r.Summary.Ignored > 0 {
+		fmt.Fprintf(w, "\n**** Ignored differences ****\n")
+		for _, file := range append(append([]FileReport{}, r.Ignored...), r.Files...) {
+			if file.IgnoreReason != "" {
+				fmt.Fprintf(w, "%s (%s): %s\n", file.Origin, file.Status, file.IgnoreReason)
+			}
+			for _, change := range file.Ignored {
+				fmt.Fprintf(w, "%s: %s %s: %s\n", file.Origin, change.Path, change.Kind, change.IgnoreReason)
+			}
+		}
+	}
+}
+
+func summarizeSeverities(changes []Change) string {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Severity]++
+	}
+	var summary []string
+	for _, severity := range []string{SeverityCritical, SeverityWarning, SeverityInfo} {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	return strings.Join(summary, ", ")
+}
+
+func summarizeThreeWay(file FileReport) string {
+	counts := map[string]int{ThreeWayCarriedOver: len(file.CarriedOver)}
+	for _, change := range file.Changes {
+		counts[change.ThreeWay]++
+	}
+	var summary []string
+	for _, class := range []string{ThreeWayCarriedOver, ThreeWayLost, ThreeWayDestinationOnly, ThreeWayConflict} {
+		if counts[class] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[class], strings.Replace(class, "_", " ", -1)))
+		}
+	}
+	return strings.Join(summary, ", ")
+}
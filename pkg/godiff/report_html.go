@@ -0,0 +1,357 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HtmlFormat writes the report as a self-contained HTML page.
+const HtmlFormat = "html"
+
+// htmlRow is one line of a side by side diff.
+type htmlRow struct {
+	Kind     string
+	Severity string
+	Label    string
+	Origin   template.HTML
+	Dest     template.HTML
+}
+
+type htmlFile struct {
+	Name   string
+	Report FileReport
+	Rows   []htmlRow
+}
+
+type htmlDir struct {
+	Name  string
+	Dirs  []*htmlDir
+	Files []htmlFile
+}
+
+type htmlService struct {
+	Name              string
+	Identical         int
+	Equivalent        int
+	Modified          int
+	Missing           int
+	OnlyInDestination int
+	TypeMismatch      int
+	Changes           int
+	Critical          int
+}
+
+type htmlReport struct {
+	Report   *Report
+	Services []htmlService
+	Root     *htmlDir
+}
+
+func serviceOf(root string, path string) string {
+	/*
+		Return the service a path belongs to: the first directory under the
+		compared root, as laid out by the pull command.
+	*/
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(root)
+	}
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) == 1 || parts[0] == "." {
+		return filepath.Base(root)
+	}
+	return parts[0]
+}
+
+func displayValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return formatValue(value)
+}
+
+func highlight(org string, dest string) (template.HTML, template.HTML) {
+	// Mark the part of both lines between their common prefix and suffix
+	prefix := 0
+	for prefix < len(org) && prefix < len(dest) && org[prefix] == dest[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(org)-prefix && suffix < len(dest)-prefix &&
+		org[len(org)-1-suffix] == dest[len(dest)-1-suffix] {
+		suffix++
+	}
+	mark := func(s string) template.HTML {
+		middle := s[prefix : len(s)-suffix]
+		if middle == "" {
+			return template.HTML(template.HTMLEscapeString(s))
+		}
+		return template.HTML(template.HTMLEscapeString(s[:prefix]) +
+			"<mark>" + template.HTMLEscapeString(middle) + "</mark>" +
+			template.HTMLEscapeString(s[len(s)-suffix:]))
+	}
+	return mark(org), mark(dest)
+}
+
+func escape(s string) template.HTML {
+	return template.HTML(template.HTMLEscapeString(s))
+}
+
+func changeRows(changes []Change) []htmlRow {
+	var rows []htmlRow
+	for _, change := range changes {
+		label := change.Path
+		if change.Section != "" {
+			label = "[" + change.Section + "] " + change.Key
+		}
+		row := htmlRow{Kind: change.Kind, Severity: change.Severity, Label: label}
+		org, dest := displayValue(change.Old), displayValue(change.New)
+		switch change.Kind {
+		case ChangeModified:
+			row.Origin, row.Dest = highlight(org, dest)
+		case ChangeMoved:
+			row.Origin, row.Dest = escape("position "+org), escape("position "+dest)
+		default:
+			row.Origin, row.Dest = escape(org), escape(dest)
+		}
+		if comment := change.comment(); comment != "" {
+			row.Label += " (" + comment + ")"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func diffRows(diff string) []htmlRow {
+	/*
+		Lay a unified diff out side by side, removed lines are paired with
+		the added lines following them to highlight the changed part.
+	*/
+	var rows []htmlRow
+	var removed, added []string
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			switch {
+			case i >= len(added):
+				rows = append(rows, htmlRow{Kind: ChangeRemoved, Origin: escape(removed[i])})
+			case i >= len(removed):
+				rows = append(rows, htmlRow{Kind: ChangeAdded, Dest: escape(added[i])})
+			default:
+				org, dest := highlight(removed[i], added[i])
+				rows = append(rows, htmlRow{Kind: ChangeModified, Origin: org, Dest: dest})
+			}
+		}
+		removed, added = nil, nil
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"), line == "":
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			rows = append(rows, htmlRow{Kind: "hunk", Label: line})
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		case strings.HasPrefix(line, "\\"):
+		default:
+			flush()
+			rows = append(rows, htmlRow{Kind: "context", Origin: escape(line[1:]), Dest: escape(line[1:])})
+		}
+	}
+	flush()
+	return rows
+}
+
+func (r *Report) htmlData() htmlReport {
+	data := htmlReport{Report: r, Root: &htmlDir{Name: r.Metadata.Origin}}
+	services := make(map[string]*htmlService)
+	var names []string
+	for _, file := range append(append(append([]FileReport{}, r.Files...), r.Identical...), r.Equivalent...) {
+		name := serviceOf(r.Metadata.Origin, file.Origin)
+		service, ok := services[name]
+		if !ok {
+			service = &htmlService{Name: name}
+			services[name] = service
+			names = append(names, name)
+		}
+		switch file.Status {
+		case StatusIdentical:
+			service.Identical++
+		case StatusEquivalent:
+			service.Equivalent++
+		case StatusModified:
+			service.Modified++
+		case StatusMissing:
+			service.Missing++
+		case StatusOnlyInDestination:
+			service.OnlyInDestination++
+		case StatusTypeMismatch:
+			service.TypeMismatch++
+		}
+		service.Changes += len(file.Changes)
+		if file.Severity == SeverityCritical {
+			service.Critical++
+		}
+
+		// Place the file in the directory tree
+		rel, err := filepath.Rel(r.Metadata.Origin, file.Origin)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = file.Origin
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		dir := data.Root
+		for _, part := range parts[:len(parts)-1] {
+			var next *htmlDir
+			for _, child := range dir.Dirs {
+				if child.Name == part {
+					next = child
+				}
+			}
+			if next == nil {
+				next = &htmlDir{Name: part}
+				dir.Dirs = append(dir.Dirs, next)
+			}
+			dir = next
+		}
+		rows := changeRows(file.Changes)
+		if len(file.Changes) == 0 {
+			rows = diffRows(file.Diff)
+		}
+		dir.Files = append(dir.Files, htmlFile{Name: parts[len(parts)-1], Report: file, Rows: rows})
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data.Services = append(data.Services, *services[name])
+	}
+	return data
+}
+
+func (r *Report) writeHtml(w io.Writer) error {
+	return htmlTemplate.Execute(w, r.htmlData())
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>os-diff report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.diff { width: 100%; table-layout: fixed; font-family: monospace; font-size: 0.9em; }
+.diff td { white-space: pre-wrap; word-break: break-all; }
+.diff td.label { width: 25%; }
+tr.removed td.org, tr.changed td.org, tr.type_changed td.org { background: #fdd; }
+tr.added td.dest, tr.changed td.dest, tr.type_changed td.dest { background: #dfd; }
+tr.moved td, tr.renamed td { background: #ffd; }
+tr.hunk td { background: #eef; color: #557; }
+mark { background: #fb4; }
+details { margin-left: 1.2em; }
+summary { cursor: pointer; }
+.status { font-size: 0.8em; padding: 0 0.4em; border-radius: 0.3em; color: #fff; }
+.status.modified { background: #c80; }
+.status.missing { background: #c33; }
+.status.only_in_destination { background: #36c; }
+.status.identical { background: #393; }
+.status.equivalent { background: #696; }
+.status.type_mismatch { background: #63c; }
+.severity { font-size: 0.8em; font-weight: bold; }
+.severity.critical { color: #c00; }
+.severity.warning { color: #a60; }
+.severity.info { color: #667; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>os-diff report</h1>
+<table>
+<tr><th>Origin</th><td>{{.Report.Metadata.Origin}}</td></tr>
+<tr><th>Destination</th><td>{{.Report.Metadata.Destination}}</td></tr>
+<tr><th>Started</th><td>{{.Report.Metadata.StartTime}}</td></tr>
+<tr><th>Finished</th><td>{{.Report.Metadata.EndTime}}</td></tr>
+</table>
+
+<h2>Services</h2>
+<table>
+<tr><th>Service</th><th>Identical files</th><th>Equivalent files</th><th>Modified files</th><th>Only in origin</th><th>Only in destination</th><th>Type mismatch</th><th>Changes</th><th>Critical</th></tr>
+{{range .Services}}<tr><td>{{.Name}}</td><td>{{.Identical}}</td><td>{{.Equivalent}}</td><td>{{.Modified}}</td><td>{{.Missing}}</td><td>{{.OnlyInDestination}}</td><td>{{.TypeMismatch}}</td><td>{{.Changes}}</td><td>{{.Critical}}</td></tr>
+{{end}}<tr><th>Total</th><th>{{.Report.Summary.Identical}}</th><th>{{.Report.Summary.Equivalent}}</th><th>{{.Report.Summary.Modified}}</th><th>{{.Report.Summary.Missing}}</th><th>{{.Report.Summary.OnlyInDestination}}</th><th>{{.Report.Summary.TypeMismatch}}</th><th>{{.Report.Summary.Changes}}</th><th>{{.Report.Summary.Critical}}</th></tr>
+</table>
+
+<h2>Files</h2>
+<p>Show:
+<label><input type="checkbox" class="filter" value="missing" checked> only in origin</label>
+<label><input type="checkbox" class="filter" value="only_in_destination" checked> only in destination</label>
+<label><input type="checkbox" class="filter" value="identical"> identical</label>
+<label><input type="checkbox" class="filter" value="equivalent"> equivalent</label>
+<label><input type="checkbox" class="filter" value="changed" checked> changed</label>
+<label><input type="checkbox" class="filter" value="added" checked> added</label>
+<label><input type="checkbox" class="filter" value="removed" checked> removed</label>
+</p>
+{{template "dir" .Root}}
+
+<script>
+// Hide the rows of unchecked kinds, and the files left without visible rows
+function applyFilters() {
+  var shown = {};
+  document.querySelectorAll("input.filter").forEach(function (box) { shown[box.value] = box.checked; });
+  var group = function (kind) {
+    if (kind === "added" || kind === "removed") { return kind; }
+    return "changed";
+  };
+  document.querySelectorAll("tr[data-kind]").forEach(function (row) {
+    var kind = row.getAttribute("data-kind");
+    row.classList.toggle("hidden", kind !== "context" && kind !== "hunk" && !shown[group(kind)]);
+  });
+  document.querySelectorAll("details.file").forEach(function (file) {
+    var status = file.getAttribute("data-status");
+    var visible = (status === "missing" || status === "only_in_destination" || status === "identical" || status === "equivalent") ? shown[status] :
+      file.querySelectorAll("tr[data-kind]:not(.hidden):not([data-kind=context]):not([data-kind=hunk])").length > 0 || (status !== "modified" && shown.changed);
+    file.classList.toggle("hidden", !visible);
+  });
+}
+document.querySelectorAll("input.filter").forEach(function (box) { box.addEventListener("change", applyFilters); });
+</script>
+</body>
+</html>
+{{define "dir"}}<details open><summary>{{.Name}}/</summary>
+{{range .Dirs}}{{template "dir" .}}{{end}}
+{{range .Files}}<details class="file{{if or (eq .Report.Status "identical") (eq .Report.Status "equivalent")}} hidden{{end}}" data-status="{{.Report.Status}}"><summary>{{.Name}} <span class="status {{.Report.Status}}">{{.Report.Status}}</span> <span class="severity {{.Report.Severity}}">{{.Report.Severity}}</span>{{if .Report.Type}} ({{.Report.Type}}){{end}}</summary>
+{{if .Report.Notes}}<ul class="notes">{{range .Report.Notes}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if eq .Report.Status "modified"}}<table class="diff">
+<tr><th class="label">Path</th><th>{{.Report.Origin}}</th><th>{{.Report.Destination}}</th></tr>
+{{range .Rows}}<tr class="{{.Kind}}" data-kind="{{.Kind}}"><td class="label">{{if .Severity}}<span class="severity {{.Severity}}">{{.Severity}}</span> {{end}}{{.Label}}</td><td class="org">{{.Origin}}</td><td class="dest">{{.Dest}}</td></tr>
+{{end}}</table>
+{{else if eq .Report.Status "missing"}}<p>{{.Report.Origin}} has no counterpart {{.Report.Destination}}</p>
+{{else if eq .Report.Status "only_in_destination"}}<p>{{.Report.Destination}} has no counterpart {{.Report.Origin}}</p>
+{{else if eq .Report.Status "identical"}}<p>{{.Report.Origin}} and {{.Report.Destination}} are identical</p>
+{{else if eq .Report.Status "equivalent"}}<p>{{.Report.Origin}} and {{.Report.Destination}} are equivalent</p>
+{{else}}<p>{{.Report.Origin}} and {{.Report.Destination}} are not both files or both directories</p>
+{{end}}</details>
+{{end}}</details>
+{{end}}`))
@@ -0,0 +1,97 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want [2]template.HTML
+	}{
+		{name: "changed middle", org: "workers = 5", dest: "workers = 8",
+			want: [2]template.HTML{"workers = <mark>5</mark>", "workers = <mark>8</mark>"}},
+		{name: "inserted part", org: "ab", dest: "axb", want: [2]template.HTML{"ab", "a<mark>x</mark>b"}},
+		{name: "same", org: "a", dest: "a", want: [2]template.HTML{"a", "a"}},
+		{name: "escaped", org: "<a>", dest: "<b>",
+			want: [2]template.HTML{"&lt;<mark>a</mark>&gt;", "&lt;<mark>b</mark>&gt;"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			org, dest := highlight(test.org, test.dest)
+			if org != test.want[0] || dest != test.want[1] {
+				t.Errorf("highlight() = %q, %q, want %q, %q", org, dest, test.want[0], test.want[1])
+			}
+		})
+	}
+}
+
+func TestDiffRows(t *testing.T) {
+	diff := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n-c\n+x\n d\n\\ No newline at end of file\n"
+	var kinds []string
+	for _, row := range diffRows(diff) {
+		kinds = append(kinds, row.Kind)
+	}
+	want := "hunk context changed removed context"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("diffRows() kinds = %q, want %q", got, want)
+	}
+}
+
+func TestServiceOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/tmp/origin/nova/nova.conf", want: "nova"},
+		{path: "/tmp/origin/setup.cfg", want: "origin"},
+		{path: "/elsewhere/nova.conf", want: "origin"},
+	}
+	for _, test := range tests {
+		if got := serviceOf("/tmp/origin", test.path); got != test.want {
+			t.Errorf("serviceOf(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestWriteHtml(t *testing.T) {
+	r := sampleReport()
+	r.Files[0].Changes[0].New = "<script>alert(1)</script>"
+	var buf bytes.Buffer
+	if err := r.Write(&buf, HtmlFormat); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{"<!DOCTYPE html>", "nova.conf", "&lt;script&gt;", `class="notes"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report has no %q", want)
+		}
+	}
+	// Self-contained: nothing is loaded from elsewhere
+	for _, unwanted := range []string{"<script>alert", " src=", "<link"} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("report has %q", unwanted)
+		}
+	}
+}
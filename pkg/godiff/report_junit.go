@@ -0,0 +1,148 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// JunitFormat writes the report as JUnit XML, one test suite per service
+// and one test case per file.
+const JunitFormat = "junit"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",cdata"`
+}
+
+func junitFailureFor(file FileReport) *junitFailure {
+	switch file.Status {
+	case StatusMissing:
+		return &junitFailure{
+			Message: fmt.Sprintf("%s is missing", file.Destination),
+			Type:    file.Status,
+			Body:    fmt.Sprintf("%s has no counterpart %s\n", file.Origin, file.Destination),
+		}
+	case StatusOnlyInDestination:
+		return &junitFailure{
+			Message: fmt.Sprintf("%s is only in the destination", file.Destination),
+			Type:    file.Status,
+			Body:    fmt.Sprintf("%s has no counterpart %s\n", file.Destination, file.Origin),
+		}
+	case StatusTypeMismatch:
+		return &junitFailure{
+			Message: fmt.Sprintf("%s and %s are not of the same type", file.Origin, file.Destination),
+			Type:    file.Status,
+			Body:    fmt.Sprintf("%s and %s are not both files or both directories\n", file.Origin, file.Destination),
+		}
+	}
+	failure := &junitFailure{Message: "Files differ", Type: file.Status, Body: file.Diff}
+	if len(file.Changes) > 0 {
+		failure.Message = summarizeChanges(file.Changes)
+		failure.Body = strings.Join(formatChanges(file.Changes), "")
+	}
+	return failure
+}
+
+func (r *Report) writeJunit(w io.Writer) error {
+	/*
+		Write a test suite per service with a failed test case per file
+		with differences, the failure message starts with the severity of
+		the file, and a passing test case per identical or equivalent
+		file.
+	*/
+	suites := junitTestSuites{Name: "os-diff"}
+	index := make(map[string]int)
+	for _, file := range append(append(append([]FileReport{}, r.Files...), r.Identical...), r.Equivalent...) {
+		service := serviceOf(r.Metadata.Origin, file.Origin)
+		i, ok := index[service]
+		if !ok {
+			i = len(suites.Suites)
+			index[service] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: service, Timestamp: r.Metadata.StartTime})
+		}
+		name, err := filepath.Rel(r.Metadata.Origin, file.Origin)
+		if err != nil || strings.HasPrefix(name, "..") {
+			name = file.Origin
+		}
+		suite := &suites.Suites[i]
+		suite.Tests++
+		suites.Tests++
+		if file.Status == StatusIdentical || file.Status == StatusEquivalent {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: name, Classname: service})
+			continue
+		}
+		failure := junitFailureFor(file)
+		if file.Severity != "" {
+			failure.Message = "[" + file.Severity + "] " + failure.Message
+		}
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      name,
+			Classname: service,
+			Failure:   failure,
+		})
+		suite.Failures++
+		suites.Failures++
+	}
+	if len(suites.Suites) == 0 {
+		// Nothing differs, still report a passing test to the CI
+		suites.Suites = append(suites.Suites, junitTestSuite{
+			Name:      filepath.Base(r.Metadata.Origin),
+			Tests:     1,
+			Timestamp: r.Metadata.StartTime,
+			Cases:     []junitTestCase{{Name: r.Metadata.Origin, Classname: filepath.Base(r.Metadata.Origin)}},
+		})
+		suites.Tests++
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteJunit(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   *Report
+		tests    int
+		failures int
+		messages map[string]string
+	}{
+		{
+			name:     "differences",
+			report:   sampleReport(),
+			tests:    6,
+			failures: 4,
+			messages: map[string]string{
+				"nova.conf":     "[warning] 1 changed",
+				"glance":        "[critical] destination/glance is missing",
+				"cinder.conf":   "[info] destination/cinder.conf is only in the destination",
+				"keystone.conf": "",
+				"heat.conf":     "",
+			},
+		},
+		{
+			name:     "no difference",
+			report:   &Report{Metadata: RunMetadata{Origin: "origin", Destination: "destination"}},
+			tests:    1,
+			messages: map[string]string{"origin": ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.report.Write(&buf, JunitFormat); err != nil {
+				t.Fatal(err)
+			}
+			var suites junitTestSuites
+			if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+				t.Fatal(err)
+			}
+			if suites.Tests != test.tests || suites.Failures != test.failures {
+				t.Errorf("tests = %d, failures = %d, want %d and %d", suites.Tests, suites.Failures, test.tests, test.failures)
+			}
+			messages := make(map[string]string)
+			for _, suite := range suites.Suites {
+				for _, c := range suite.Cases {
+					messages[c.Name] = ""
+					if c.Failure != nil {
+						messages[c.Name] = c.Failure.Message
+					}
+				}
+			}
+			for name, want := range test.messages {
+				if got, ok := messages[name]; !ok || got != want {
+					t.Errorf("test case %s message = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
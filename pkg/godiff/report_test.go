@@ -0,0 +1,141 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+func sampleReport() *Report {
+	r := &Report{Metadata: RunMetadata{Origin: "origin", Destination: "destination"}}
+	r.add(FileReport{
+		Origin: "origin/nova.conf", Destination: "destination/nova.conf",
+		Status: StatusModified, Severity: SeverityWarning, Type: IniType,
+		Changes: []Change{{
+			Path: "DEFAULT.workers", Section: "DEFAULT", Key: "workers", Kind: ChangeModified,
+			Old: json.Number("5"), New: json.Number("8"), Severity: SeverityWarning,
+		}},
+	})
+	r.add(FileReport{Origin: "origin/glance", Destination: "destination/glance", Status: StatusMissing, Severity: SeverityCritical, Directory: true})
+	r.add(FileReport{Origin: "origin/cinder.conf", Destination: "destination/cinder.conf", Status: StatusOnlyInDestination, Severity: SeverityInfo})
+	r.add(FileReport{Origin: "origin/swift", Destination: "destination/swift", Status: StatusTypeMismatch, Severity: SeverityWarning})
+	r.identical(FileReport{Origin: "origin/keystone.conf", Destination: "destination/keystone.conf", Status: StatusIdentical})
+	r.equivalent(FileReport{
+		Origin: "origin/heat.conf", Destination: "destination/heat.conf", Status: StatusEquivalent,
+		Notes: []string{"option DEFAULT.max_pool_size spelled max-pool-size in destination"},
+	})
+	return r
+}
+
+func TestReportSummary(t *testing.T) {
+	want := Summary{
+		Identical: 1, Equivalent: 1, Modified: 1, Missing: 1, OnlyInDestination: 1, TypeMismatch: 1,
+		Changes: 1, Critical: 1, Warning: 2, Info: 1,
+	}
+	if got := sampleReport().Summary; got != want {
+		t.Errorf("Summary = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: TextFormat, want: []string{
+			"**** Missing files or directories ****\norigin/glance (critical)",
+			"**** Only in destination ****\ndestination/cinder.conf (info)",
+			"origin/nova.conf: ",
+			"**** Notes ****\norigin/heat.conf: option DEFAULT.max_pool_size",
+			"1 identical, 1 equivalent, 1 modified, 1 only in origin, 1 only in destination, 1 type mismatch",
+		}},
+		{format: JsonFormat},
+		{format: YamlFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := sampleReport().Write(&buf, test.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("report has no %q:\n%s", want, buf.String())
+				}
+			}
+			if test.format == TextFormat {
+				return
+			}
+			// Both documents have the same shape, numbers stay numbers
+			var doc struct {
+				Summary Summary
+				Files   []struct {
+					Status  string
+					Changes []map[string]interface{}
+				}
+			}
+			var err error
+			if test.format == JsonFormat {
+				err = json.Unmarshal(buf.Bytes(), &doc)
+			} else {
+				err = yaml.Unmarshal(buf.Bytes(), &doc)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Summary.Modified != 1 || len(doc.Files) != 4 || doc.Files[0].Status != StatusModified {
+				t.Fatalf("report = %+v", doc)
+			}
+			change := doc.Files[0].Changes[0]
+			if change["path"] != "DEFAULT.workers" || change["old"] == nil || change["old"] == "5" || change["new"] == "8" {
+				t.Errorf("change = %v, want DEFAULT.workers from 5 to 8 as numbers", change)
+			}
+		})
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleReport().Write(&buf, "xml"); err == nil {
+		t.Errorf("Write() with an unknown format has no error")
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		valid  bool
+	}{
+		{format: TextFormat, valid: true},
+		{format: JsonFormat, valid: true},
+		{format: YamlFormat, valid: true},
+		{format: HtmlFormat, valid: true},
+		{format: JunitFormat, valid: true},
+		{format: "xml"},
+		{format: ""},
+	}
+	for _, test := range tests {
+		if err := ValidateFormat(test.format); (err == nil) != test.valid {
+			t.Errorf("ValidateFormat(%q) = %v, want valid %v", test.format, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Severities of the differences, from the least to the most important.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRanks = map[string]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// SeverityRule ranks the differences matching all its criteria: the
+// service, a glob on the file path, an INI section, a glob on the option
+// name, a JSON or YAML path (and what is below it) and the change kind.
+// A rule with only a service or a file ranks whole files.
+type SeverityRule struct {
+	Service  string `yaml:"service,omitempty"`
+	File     string `yaml:"file,omitempty"`
+	Section  string `yaml:"section,omitempty"`
+	Key      string `yaml:"key,omitempty"`
+	Path     string `yaml:"path,omitempty"`
+	Kind     string `yaml:"kind,omitempty"`
+	Severity string `yaml:"severity"`
+}
+
+// SeverityRules is the content of a severity rules file.
+type SeverityRules struct {
+	Severity []SeverityRule `yaml:"severity"`
+}
+
+// DefaultSeverityRules is the built-in catalog, user rules come first.
+// Differences matching no rule are warnings.
+var DefaultSeverityRules = []SeverityRule{
+	{Section: "keystone_authtoken", Kind: ChangeRemoved, Severity: SeverityCritical},
+	{Key: "auth_strategy", Severity: SeverityCritical},
+	{Key: "auth_type", Severity: SeverityCritical},
+	{Key: "auth_url", Severity: SeverityCritical},
+	{Key: "www_authenticate_uri", Severity: SeverityCritical},
+	{Key: "transport_url", Severity: SeverityCritical},
+	{Section: "database", Key: "connection", Severity: SeverityCritical},
+	{Section: "api_database", Key: "connection", Severity: SeverityCritical},
+	{Key: "enabled_backends", Severity: SeverityCritical},
+	{Key: "debug", Severity: SeverityInfo},
+	{Key: "verbose", Severity: SeverityInfo},
+	{Key: "log_dir", Severity: SeverityInfo},
+	{Key: "log_file", Severity: SeverityInfo},
+	{Key: "log_config_append", Severity: SeverityInfo},
+	{Key: "use_syslog", Severity: SeverityInfo},
+	{Key: "use_stderr", Severity: SeverityInfo},
+	{Key: "use_journal", Severity: SeverityInfo},
+	{Key: "default_log_levels", Severity: SeverityInfo},
+	{Key: "logging_*", Severity: SeverityInfo},
+	{Key: "*_log_level", Severity: SeverityInfo},
+	{File: "logging.conf", Severity: SeverityInfo},
+}
+
+// ValidateSeverity checks severity is a known severity.
+func ValidateSeverity(severity string) error {
+	if _, ok := severityRanks[severity]; !ok {
+		return fmt.Errorf("Unknown severity: %s, expected info, warning or critical", severity)
+	}
+	return nil
+}
+
+func severityAtLeast(severity string, min string) bool {
+	return severityRanks[severity] >= severityRanks[min]
+}
+
+func maxSeverity(a string, b string) string {
+	if severityRanks[b] > severityRanks[a] {
+		return b
+	}
+	return a
+}
+
+// LoadSeverityRules reads and checks the rules of the YAML file at path.
+func LoadSeverityRules(path string) ([]SeverityRule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("Failed to open file: '" + path + "'. " + err.Error())
+	}
+	var rules SeverityRules
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", path, err)
+	}
+	if err := CheckSeverityRules(rules.Severity); err != nil {
+		return nil, fmt.Errorf("Invalid severity rule in %s: %s", path, err)
+	}
+	return rules.Severity, nil
+}
+
+// CheckSeverityRules checks the severities and the patterns of the rules.
+func CheckSeverityRules(rules []SeverityRule) error {
+	for i, rule := range rules {
+		if err := ValidateSeverity(rule.Severity); err != nil {
+			return fmt.Errorf("rule %d: %s", i+1, err)
+		}
+		for _, pattern := range []string{rule.File, rule.Key} {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d has an invalid pattern: %s", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *SeverityRule) fileRule() bool {
+	return r.Section == "" && r.Key == "" && r.Path == "" && r.Kind == ""
+}
+
+func (r *SeverityRule) matchesChange(change Change) bool {
+	if r.Section != "" && canonicalSection(r.Section) != change.Section {
+		return false
+	}
+	if r.Key != "" {
+		name := changeName(change)
+		if name == "" {
+			return false
+		}
+		if match, _ := filepath.Match(canonicalOption(strings.ToLower(r.Key)), canonicalOption(strings.ToLower(name))); !match {
+			return false
+		}
+	}
+	if r.Path != "" && change.Path != r.Path &&
+		!strings.HasPrefix(change.Path, r.Path+".") && !strings.HasPrefix(change.Path, r.Path+"[") {
+		return false
+	}
+	return r.Kind == "" || r.Kind == change.Kind
+}
+
+func changeSeverity(rules []SeverityRule, service string, path string, change Change) string {
+	for _, catalog := range [][]SeverityRule{rules, DefaultSeverityRules} {
+		for i := range catalog {
+			rule := &catalog[i]
+			if fileMatches(rule.Service, rule.File, service, path) && (rule.fileRule() || rule.matchesChange(change)) {
+				return rule.Severity
+			}
+		}
+	}
+	return SeverityWarning
+}
+
+func fileSeverity(rules []SeverityRule, service string, path string) string {
+	for _, catalog := range [][]SeverityRule{rules, DefaultSeverityRules} {
+		for i := range catalog {
+			rule := &catalog[i]
+			if rule.fileRule() && fileMatches(rule.Service, rule.File, service, path) {
+				return rule.Severity
+			}
+		}
+	}
+	return SeverityWarning
+}
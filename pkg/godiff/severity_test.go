@@ -0,0 +1,125 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import "testing"
+
+func TestChangeSeverity(t *testing.T) {
+	rules := []SeverityRule{
+		{Service: "nova", Key: "debug", Severity: SeverityCritical},
+		{File: "policy.yaml", Path: "rules", Severity: SeverityInfo},
+	}
+	tests := []struct {
+		name    string
+		service string
+		path    string
+		change  Change
+		want    string
+	}{
+		{name: "user rule first", service: "nova", path: "nova.conf", change: iniChange("DEFAULT", "debug", ChangeModified, "a", "b"), want: SeverityCritical},
+		{name: "catalog", service: "cinder", path: "cinder.conf", change: iniChange("DEFAULT", "debug", ChangeModified, "a", "b"), want: SeverityInfo},
+		{name: "catalog glob", service: "cinder", path: "cinder.conf", change: iniChange("DEFAULT", "oslo_log_level", ChangeModified, "a", "b"), want: SeverityInfo},
+		{name: "catalog section", service: "nova", path: "nova.conf", change: iniChange("database", "connection", ChangeModified, "a", "b"), want: SeverityCritical},
+		{name: "other section", service: "nova", path: "nova.conf", change: iniChange("cache", "connection", ChangeModified, "a", "b"), want: SeverityWarning},
+		{name: "catalog kind", service: "nova", path: "nova.conf", change: iniChange("keystone_authtoken", "", ChangeRemoved, nil, nil), want: SeverityCritical},
+		{name: "other kind", service: "nova", path: "nova.conf", change: iniChange("keystone_authtoken", "memcached_servers", ChangeAdded, nil, "a"), want: SeverityWarning},
+		{name: "spelling", service: "nova", path: "nova.conf", change: iniChange("DEFAULT", "transport-url", ChangeModified, "a", "b"), want: SeverityCritical},
+		{name: "tree key", service: "nova", path: "nova.yaml", change: Change{Path: "spec.transport_url", Kind: ChangeModified}, want: SeverityCritical},
+		{name: "path prefix", service: "nova", path: "/etc/nova/policy.yaml", change: Change{Path: "rules.admin", Kind: ChangeModified}, want: SeverityInfo},
+		{name: "whole file", service: "nova", path: "/etc/nova/logging.conf", change: iniChange("logger_root", "level", ChangeModified, "a", "b"), want: SeverityInfo},
+		{name: "no rule", service: "nova", path: "nova.conf", change: iniChange("DEFAULT", "workers", ChangeModified, "a", "b"), want: SeverityWarning},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := changeSeverity(rules, test.service, test.path, test.change); got != test.want {
+				t.Errorf("changeSeverity() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckSeverityRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  SeverityRule
+		valid bool
+	}{
+		{name: "valid", rule: SeverityRule{Key: "debug", Severity: SeverityInfo}, valid: true},
+		{name: "unknown severity", rule: SeverityRule{Key: "debug", Severity: "minor"}},
+		{name: "invalid key pattern", rule: SeverityRule{Key: "[", Severity: SeverityInfo}},
+		{name: "invalid file pattern", rule: SeverityRule{File: "[", Severity: SeverityInfo}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckSeverityRules([]SeverityRule{test.rule}); (err == nil) != test.valid {
+				t.Errorf("CheckSeverityRules() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestMinSeverity(t *testing.T) {
+	org := "[DEFAULT]\ndebug = true\nworkers = 2\ntransport_url = rabbit://h1/\n"
+	dest := "[DEFAULT]\ndebug = false\nworkers = 4\ntransport_url = rabbit://h2/\n"
+	tests := []struct {
+		min      string
+		changes  string
+		filtered int
+	}{
+		{changes: "changed DEFAULT.debug; changed DEFAULT.workers; changed DEFAULT.transport_url"},
+		{min: SeverityWarning, changes: "changed DEFAULT.workers; changed DEFAULT.transport_url", filtered: 1},
+		{min: SeverityCritical, changes: "changed DEFAULT.transport_url", filtered: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.min, func(t *testing.T) {
+			f := CompareFileNames{Origin: "nova.conf", Destination: "nova.conf", MinSeverity: test.min}
+			if err := f.compareIni([]byte(org), []byte(dest), f.Origin, f.Destination); err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(f.Changes); got != test.changes {
+				t.Errorf("Changes = %q, want %q", got, test.changes)
+			}
+			if f.Filtered != test.filtered {
+				t.Errorf("Filtered = %d, want %d", f.Filtered, test.filtered)
+			}
+		})
+	}
+}
+
+func TestFailsOnSeverity(t *testing.T) {
+	tests := []struct {
+		failOn string
+		fails  bool
+	}{
+		{failOn: FailOnAny, fails: true},
+		{failOn: FailOnNever},
+		{failOn: FailOnMissing, fails: true},
+		{failOn: SeverityInfo, fails: true},
+		{failOn: SeverityWarning, fails: true},
+		{failOn: SeverityCritical, fails: true},
+	}
+	for _, test := range tests {
+		if got := sampleReport().Fails(test.failOn); got != test.fails {
+			t.Errorf("Fails(%s) = %v, want %v", test.failOn, got, test.fails)
+		}
+	}
+	r := &Report{}
+	r.add(FileReport{Status: StatusModified, Severity: SeverityWarning})
+	if r.Fails(SeverityCritical) || !r.Fails(SeverityWarning) || r.Fails(FailOnMissing) {
+		t.Errorf("Fails() of a warning does not follow the thresholds")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Classes of the changes of a three-way comparison, telling which side
+// moved away from the base.
+const (
+	ThreeWayCarriedOver     = "carried_over"
+	ThreeWayLost            = "lost"
+	ThreeWayDestinationOnly = "destination_only"
+	ThreeWayConflict        = "conflict"
+)
+
+var threeWayLabels = map[string]string{
+	ThreeWayCarriedOver:     "customized in origin and carried over",
+	ThreeWayLost:            "customized in origin but lost",
+	ThreeWayDestinationOnly: "changed only in destination",
+	ThreeWayConflict:        "conflict",
+}
+
+func (f *CompareFileNames) compareBase(orgContent []byte, destContent []byte) error {
+	/*
+		Find the changes of the origin and the destination from the base
+		file, a missing base file is read as empty.
+	*/
+	baseContent, err := ioutil.ReadFile(f.Base)
+	if os.IsNotExist(err) {
+		log.Info("Base file is missing: ", f.Base, ", compare with an empty file")
+	} else if err != nil {
+		log.Error("Failed to read file", f.Base, "\n")
+		return errors.New("Failed to open file: '" + f.Base + "'. " + err.Error())
+	}
+	log.Info("Compare with the base file: ", f.Base)
+	f.baseOrigin, err = f.baseChanges(f.Origin, baseContent, orgContent)
+	if err != nil {
+		return err
+	}
+	f.baseDest, err = f.baseChanges(f.Destination, baseContent, destContent)
+	return err
+}
+
+func (f *CompareFileNames) baseChanges(path string, baseContent []byte, content []byte) ([]Change, error) {
+	// Both sides are translated into the destination layout
+	base := CompareFileNames{
+		Origin:         f.Base,
+		Destination:    path,
+		MergeKeys:      f.MergeKeys,
+		Service:        f.Service,
+		Mapping:        f.Mapping,
+		Metadata:       f.Metadata,
+		OriginMetadata: f.OriginMetadata,
+		skipDiffFile:   true,
+		mapDestination: path == f.Origin,
+		quiet:          true,
+	}
+	if err := base.compareContents(baseContent, content); err != nil {
+		return nil, err
+	}
+	return base.Changes, nil
+}
+
+func touches(changes []Change, path string) bool {
+	// Changes touch the values below and above their path
+	for _, change := range changes {
+		if change.Path == path || change.Path == "" || path == "" ||
+			strings.HasPrefix(change.Path, path+".") || strings.HasPrefix(change.Path, path+"[") ||
+			strings.HasPrefix(path, change.Path+".") || strings.HasPrefix(path, change.Path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CompareFileNames) classify(changes []Change) []Change {
+	/*
+		Set the three-way class of the changes between the origin and the
+		destination, and keep the customizations of the origin found as is
+		in the destination in CarriedOver.
+	*/
+	classified := make([]Change, len(changes))
+	for i, change := range changes {
+		inOrigin, inDest := touches(f.baseOrigin, change.Path), touches(f.baseDest, change.Path)
+		switch {
+		case inOrigin && inDest:
+			change.ThreeWay = ThreeWayConflict
+		case inOrigin:
+			change.ThreeWay = ThreeWayLost
+		case inDest:
+			change.ThreeWay = ThreeWayDestinationOnly
+		default:
+			// Neither comparison with the base reached this path, the
+			// change is left unclassified
+			log.Info("Difference at: ", change.Path, " not found against the base: ", f.Base)
+		}
+		classified[i] = change
+	}
+	f.CarriedOver = nil
+	for _, change := range f.baseOrigin {
+		if !touches(changes, change.Path) {
+			change.ThreeWay = ThreeWayCarriedOver
+			f.CarriedOver = append(f.CarriedOver, change)
+		}
+	}
+	return classified
+}
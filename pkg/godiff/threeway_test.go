@@ -0,0 +1,82 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	changed := func(paths ...string) []Change {
+		var changes []Change
+		for _, path := range paths {
+			changes = append(changes, Change{Path: path, Kind: ChangeModified})
+		}
+		return changes
+	}
+	tests := []struct {
+		name        string
+		baseOrigin  []Change
+		baseDest    []Change
+		path        string
+		want        string
+		carriedOver int
+	}{
+		{name: "lost", baseOrigin: changed("a"), path: "a", want: ThreeWayLost},
+		{name: "destination only", baseDest: changed("a"), path: "a", want: ThreeWayDestinationOnly},
+		{name: "conflict", baseOrigin: changed("a"), baseDest: changed("a"), path: "a", want: ThreeWayConflict},
+		{name: "parent path", baseOrigin: changed("a"), baseDest: changed("a.b"), path: "a.b.c", want: ThreeWayConflict},
+		{name: "list item", baseDest: changed("a[0]"), path: "a", want: ThreeWayDestinationOnly},
+		{name: "neither side", baseOrigin: changed("b"), baseDest: changed("c"), path: "a", carriedOver: 1},
+		{name: "carried over", baseOrigin: changed("a", "b"), path: "a", want: ThreeWayLost, carriedOver: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Base: "base.conf", baseOrigin: test.baseOrigin, baseDest: test.baseDest}
+			classified := f.classify(changed(test.path))
+			if got := classified[0].ThreeWay; got != test.want {
+				t.Errorf("ThreeWay = %q, want %q", got, test.want)
+			}
+			if len(f.CarriedOver) != test.carriedOver {
+				t.Errorf("CarriedOver = %v, want %d changes", f.CarriedOver, test.carriedOver)
+			}
+		})
+	}
+}
+
+func TestCompareBaseQuiet(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "base.conf")
+	if err := ioutil.WriteFile(base, []byte("[DEFAULT]\ndebug = false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(ioutil.Discard)
+	f := CompareFileNames{Origin: "org.conf", Destination: "dest.conf", Base: base}
+	if err := f.compareBase([]byte("[DEFAULT]\ndebug = true\n"), []byte("[DEFAULT]\nverbose = true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.baseOrigin) == 0 || len(f.baseDest) == 0 {
+		t.Fatalf("base changes = %v and %v, want both sides changed", f.baseOrigin, f.baseDest)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Difference detected")) {
+		t.Errorf("base comparisons logged differences:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,551 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Kinds of change reported by the structured comparators. Changes are
+// always expressed from the origin to the destination.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "changed"
+	ChangeType     = "type_changed"
+	ChangeMoved    = "moved"
+	ChangeRenamed  = "renamed"
+)
+
+// Change describes one difference found at Path between the origin and
+// the destination. Old is unset for added values, New for removed ones.
+// For moved list items Old and New hold the origin and destination index.
+// INI changes also carry their Section and Key, Key is empty when the
+// whole section is added or removed. Note gives details for the reader,
+// such as an option spelled differently on both sides. IgnoreReason is
+// set on changes suppressed by an ignore rule. Severity ranks the change.
+// ThreeWay tells which side moved away from the base in a three-way
+// comparison.
+type Change struct {
+	Path         string
+	Section      string
+	Key          string
+	Kind         string
+	Old          interface{}
+	New          interface{}
+	Note         string
+	IgnoreReason string
+	Severity     string
+	ThreeWay     string
+}
+
+func parseYaml(data []byte) (interface{}, error) {
+	// Decode mappings as MapSlice so the original key order is kept, at
+	// any depth and whatever the top-level value is
+	var doc orderedNode
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.value == nil {
+		// Empty documents compare as empty mappings
+		return yaml.MapSlice{}, nil
+	}
+	return doc.value, nil
+}
+
+// orderedNode decodes a YAML value with its mappings as MapSlice, yaml
+// decodes them as Go maps unless the target is a MapSlice.
+type orderedNode struct {
+	value interface{}
+}
+
+func (n *orderedNode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Sequences first, yaml decodes them into a MapSlice without error
+	var sequence []orderedNode
+	if err := unmarshal(&sequence); err == nil {
+		list := make([]interface{}, len(sequence))
+		for i, item := range sequence {
+			list[i] = item.value
+		}
+		n.value = list
+		return nil
+	}
+	var mapping yaml.MapSlice
+	if err := unmarshal(&mapping); err == nil {
+		n.value = mapping
+		return nil
+	}
+	return unmarshal(&n.value)
+}
+
+func yamlDocuments(data []byte) (int, error) {
+	// Count the documents of a YAML stream
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+func parseJson(data []byte) (interface{}, error) {
+	// Decode objects as MapSlice too so both formats share the comparator
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeJsonValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+func decodeJsonValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		object := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case '[':
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return nil, fmt.Errorf("unexpected delimiter %s", delim)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int, int64, uint64:
+		return "int"
+	case float64:
+		return "float"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case yaml.MapSlice:
+		return "map"
+	case []interface{}:
+		return "list"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path string, key interface{}) string {
+	name := fmt.Sprint(key)
+	if name == "" || strings.ContainsAny(name, ".[]\" ") {
+		name = "[" + strconv.Quote(name) + "]"
+		return path + name
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// DefaultMergeKeys are the fields identifying the items of well-known
+// Kubernetes lists, by list name.
+var DefaultMergeKeys = map[string]string{
+	"containers":     "name",
+	"initContainers": "name",
+	"env":            "name",
+	"envFrom":        "prefix",
+	"volumes":        "name",
+	"volumeMounts":   "mountPath",
+	"ports":          "containerPort",
+}
+
+// Fields tried in order to identify list items when no merge key is set.
+var mergeKeyCandidates = []string{"name", "id", "key", "uid", "mountPath"}
+
+var indexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// treeComparer walks two decoded documents. mergeKeys maps a list path,
+// without indexes, or a list name to the field identifying its items.
+type treeComparer struct {
+	mergeKeys map[string]string
+}
+
+func compareTree(org, dest interface{}, path string) []Change {
+	return (&treeComparer{mergeKeys: DefaultMergeKeys}).compare(org, dest, path)
+}
+
+func (c *treeComparer) compare(org, dest interface{}, path string) []Change {
+	/*
+		Walk org and dest recursively and return every leaf which differs,
+		addressed by its full path. Keys are visited in origin order, keys
+		only found in the destination come last in destination order.
+	*/
+	if typeName(org) != typeName(dest) {
+		return []Change{{Path: path, Kind: ChangeType, Old: org, New: dest}}
+	}
+
+	var changes []Change
+	switch org := org.(type) {
+	case yaml.MapSlice:
+		dest := dest.(yaml.MapSlice)
+		orgKeys := make(map[string]bool, len(org))
+		destIndex := make(map[string]int, len(dest))
+		for i, item := range dest {
+			destIndex[fmt.Sprint(item.Key)] = i
+		}
+		for _, item := range org {
+			key := fmt.Sprint(item.Key)
+			orgKeys[key] = true
+			if i, ok := destIndex[key]; ok {
+				changes = append(changes, c.compare(item.Value, dest[i].Value, joinPath(path, item.Key))...)
+			} else {
+				changes = append(changes, Change{Path: joinPath(path, item.Key), Kind: ChangeRemoved, Old: item.Value})
+			}
+		}
+		for _, item := range dest {
+			if !orgKeys[fmt.Sprint(item.Key)] {
+				changes = append(changes, Change{Path: joinPath(path, item.Key), Kind: ChangeAdded, New: item.Value})
+			}
+		}
+	case []interface{}:
+		changes = c.compareList(org, dest.([]interface{}), path)
+	case json.Number:
+		// 1 and 1.0 are the same JSON number
+		orgValue, orgErr := org.Float64()
+		destValue, destErr := dest.(json.Number).Float64()
+		if orgErr != nil || destErr != nil || orgValue != destValue {
+			changes = append(changes, Change{Path: path, Kind: ChangeModified, Old: org, New: dest})
+		}
+	default:
+		if !reflect.DeepEqual(org, dest) {
+			changes = append(changes, Change{Path: path, Kind: ChangeModified, Old: org, New: dest})
+		}
+	}
+	return changes
+}
+
+func (c *treeComparer) compareList(org, dest []interface{}, path string) []Change {
+	/*
+		Match the items of both lists by identity, the merge key value for
+		lists of mappings or the value itself for lists of scalars, then
+		diff the identity sequences. Lists with no usable identity are
+		compared index by index.
+	*/
+	if len(org) == 0 && len(dest) == 0 {
+		return nil
+	}
+	key := c.mergeKey(path, org, dest)
+	orgIds, orgOk := listIdentities(org, key)
+	destIds, destOk := listIdentities(dest, key)
+	if !orgOk || !destOk {
+		var changes []Change
+		for i := 0; i < len(org) || i < len(dest); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(dest) {
+				changes = append(changes, Change{Path: itemPath, Kind: ChangeRemoved, Old: org[i]})
+			} else if i >= len(org) {
+				changes = append(changes, Change{Path: itemPath, Kind: ChangeAdded, New: dest[i]})
+			} else {
+				changes = append(changes, c.compare(org[i], dest[i], itemPath)...)
+			}
+		}
+		return changes
+	}
+
+	itemPath := func(ids []string, i int) string {
+		if key != "" {
+			return fmt.Sprintf("%s[%s=%s]", path, key, ids[i])
+		}
+		return fmt.Sprintf("%s[%d]", path, i)
+	}
+
+	// Items deleted at one place and inserted at another have moved
+	script := myersDiff(orgIds, destIds)
+	deleted := make(map[string][]int)
+	for _, e := range script {
+		if e.kind == editDelete {
+			deleted[orgIds[e.org]] = append(deleted[orgIds[e.org]], e.org)
+		}
+	}
+	moved := make(map[int]bool)
+	var changes []Change
+	for _, e := range script {
+		switch e.kind {
+		case editEqual:
+			changes = append(changes, c.compare(org[e.org], dest[e.dest], itemPath(destIds, e.dest))...)
+		case editInsert:
+			id := destIds[e.dest]
+			if from := deleted[id]; len(from) > 0 {
+				deleted[id] = from[1:]
+				moved[from[0]] = true
+				changes = append(changes, Change{Path: itemPath(destIds, e.dest), Kind: ChangeMoved, Old: from[0], New: e.dest})
+				changes = append(changes, c.compare(org[from[0]], dest[e.dest], itemPath(destIds, e.dest))...)
+			} else {
+				changes = append(changes, Change{Path: itemPath(destIds, e.dest), Kind: ChangeAdded, New: dest[e.dest]})
+			}
+		}
+	}
+	for _, e := range script {
+		if e.kind == editDelete && !moved[e.org] {
+			changes = append(changes, Change{Path: itemPath(orgIds, e.org), Kind: ChangeRemoved, Old: org[e.org]})
+		}
+	}
+	return changes
+}
+
+func (c *treeComparer) mergeKey(path string, org, dest []interface{}) string {
+	listPath := indexPattern.ReplaceAllString(path, "")
+	if key, ok := c.mergeKeys[listPath]; ok {
+		return key
+	}
+	if key, ok := c.mergeKeys[listPath[strings.LastIndex(listPath, ".")+1:]]; ok {
+		return key
+	}
+	for _, candidate := range mergeKeyCandidates {
+		_, orgOk := listIdentities(org, candidate)
+		_, destOk := listIdentities(dest, candidate)
+		if orgOk && destOk && (len(org) > 0 || len(dest) > 0) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func listIdentities(list []interface{}, key string) ([]string, bool) {
+	/*
+		Return the identity of each item: the scalar value of the key field
+		which must be unique in the list, or the item itself when key is
+		empty and the list only holds scalars.
+	*/
+	ids := make([]string, len(list))
+	seen := make(map[string]bool, len(list))
+	for i, item := range list {
+		if key == "" {
+			switch item.(type) {
+			case yaml.MapSlice, []interface{}:
+				return nil, false
+			}
+			ids[i] = typeName(item) + ":" + formatValue(item)
+			continue
+		}
+		mapping, ok := item.(yaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for _, field := range mapping {
+			if fmt.Sprint(field.Key) != key {
+				continue
+			}
+			switch field.Value.(type) {
+			case yaml.MapSlice, []interface{}, nil:
+				return nil, false
+			}
+			ids[i] = fmt.Sprint(field.Value)
+			found = true
+		}
+		if !found || seen[ids[i]] {
+			return nil, false
+		}
+		seen[ids[i]] = true
+	}
+	return ids, true
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		// Quote strings which would otherwise read as another type
+		if v == "" || v == "null" || v == "~" || strings.TrimSpace(v) != v ||
+			strings.ContainsAny(v, "\n\t\"") {
+			return strconv.Quote(v)
+		}
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return strconv.Quote(v)
+		}
+		if _, err := strconv.ParseBool(v); err == nil {
+			return strconv.Quote(v)
+		}
+		return v
+	case yaml.MapSlice:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprintf("%v: %s", item.Key, formatValue(item.Value)))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+func formatIniChanges(changes []Change) []string {
+	var lines []string
+	section := ""
+	for _, change := range changes {
+		if change.Key == "" {
+			section = change.Section
+			prefix := "+"
+			if change.Kind == ChangeRemoved {
+				prefix = "-"
+			}
+			lines = append(lines, fmt.Sprintf("%s[%s]\n", prefix, change.Section))
+			continue
+		}
+		if change.Section != section {
+			section = change.Section
+			lines = append(lines, fmt.Sprintf("[%s]\n", section))
+		}
+		if comment := change.comment(); comment != "" {
+			lines = append(lines, fmt.Sprintf("# %s: %s\n", change.Key, comment))
+		}
+		switch change.Kind {
+		case ChangeRenamed:
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("-%s=%v\n", change.Key, change.Old))
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf("+%s=%v\n", change.Key, change.New))
+		default:
+			lines = append(lines, fmt.Sprintf("-%s=%v\n", change.Key, change.Old))
+			lines = append(lines, fmt.Sprintf("+%s=%v\n", change.Key, change.New))
+		}
+	}
+	return lines
+}
+
+func (c *Change) addNote(note string) {
+	if note == "" {
+		return
+	}
+	if c.Note != "" {
+		note = c.Note + ", " + note
+	}
+	c.Note = note
+}
+
+func (c *Change) comment() string {
+	// The note and the three-way class of a change
+	comment := c.Note
+	if label, ok := threeWayLabels[c.ThreeWay]; ok {
+		if comment != "" {
+			comment += ", "
+		}
+		comment += label
+	}
+	return comment
+}
+
+func formatChanges(changes []Change) []string {
+	if len(changes) > 0 && changes[0].Section != "" {
+		return formatIniChanges(changes)
+	}
+	var lines []string
+	for _, change := range changes {
+		path := change.Path
+		if path == "" {
+			path = "."
+		}
+		if label, ok := threeWayLabels[change.ThreeWay]; ok {
+			lines = append(lines, fmt.Sprintf("# %s: %s\n", path, label))
+		}
+		switch change.Kind {
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("-%s: %s\n", path, formatValue(change.Old)))
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf("+%s: %s\n", path, formatValue(change.New)))
+		case ChangeMoved:
+			lines = append(lines, fmt.Sprintf("# %s: moved from position %v to %v\n", path, change.Old, change.New))
+		case ChangeType:
+			note := change.Note
+			if note == "" {
+				note = fmt.Sprintf("type changed from %s to %s", typeName(change.Old), typeName(change.New))
+			}
+			lines = append(lines, fmt.Sprintf("# %s: %s\n", path, note))
+			fallthrough
+		default:
+			lines = append(lines, fmt.Sprintf("-%s: %s\n", path, formatValue(change.Old)))
+			lines = append(lines, fmt.Sprintf("+%s: %s\n", path, formatValue(change.New)))
+		}
+	}
+	return lines
+}
+
+func summarizeChanges(changes []Change) string {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	var summary []string
+	for _, kind := range []string{ChangeAdded, ChangeRemoved, ChangeModified, ChangeType, ChangeMoved, ChangeRenamed} {
+		if counts[kind] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+	return strings.Join(summary, ", ")
+}
@@ -0,0 +1,155 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+func describeChanges(changes []Change) string {
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, fmt.Sprintf("%s %s", change.Kind, change.Path))
+	}
+	return strings.Join(lines, "; ")
+}
+
+func TestCompareList(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want string
+	}{
+		{
+			name: "empty lists",
+			org:  `{"a": [], "b": 1}`,
+			dest: `{"a": [], "b": 2}`,
+			want: "changed b",
+		},
+		{
+			name: "one side empty",
+			org:  `{"env": []}`,
+			dest: `{"env": [{"name": "A", "value": "1"}]}`,
+			want: "added env[name=A]",
+		},
+		{
+			name: "items matched by merge key",
+			org:  `{"env": [{"name": "A", "value": "1"}, {"name": "B", "value": "2"}]}`,
+			dest: `{"env": [{"name": "B", "value": "3"}, {"name": "A", "value": "1"}]}`,
+			want: "changed env[name=B].value; moved env[name=A]",
+		},
+		{
+			name: "scalars",
+			org:  `{"l": [1, 2, 3]}`,
+			dest: `{"l": [1, 3, 4]}`,
+			want: "added l[2]; removed l[1]",
+		},
+		{
+			name: "items without identity compared by index",
+			org:  `{"l": [{"x": 1}, {"x": 2}]}`,
+			dest: `{"l": [{"x": 1}]}`,
+			want: "removed l[1]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			org, err := parseJson([]byte(test.org))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dest, err := parseJson([]byte(test.dest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(compareTree(org, dest, "")); got != test.want {
+				t.Errorf("compareTree() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareYamlDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want bool
+	}{
+		{name: "single document", org: "---\na: 1\n", dest: "a: 1\n"},
+		{name: "same documents", org: "a: 1\n---\nb: 2\n", dest: "a: 1\n---\nb: 2\n"},
+		{name: "second document differs", org: "a: 1\n---\nb: 2\n", dest: "a: 1\n---\nb: 3\n", want: true},
+		{name: "document added", org: "a: 1\n", dest: "a: 1\n---\nb: 2\n", want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := CompareFileNames{Origin: "org.yaml", Destination: "dest.yaml"}
+			if err := f.CompareYamlFiles([]byte(test.org), []byte(test.dest)); err != nil {
+				t.Fatal(err)
+			}
+			if got := f.HasDifferences(); got != test.want {
+				t.Errorf("HasDifferences() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseYaml(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want interface{}
+	}{
+		{
+			name: "mapping",
+			data: "b: 1\na: {d: 2, c: 3}\n",
+			want: yaml.MapSlice{
+				{Key: "b", Value: 1},
+				{Key: "a", Value: yaml.MapSlice{{Key: "d", Value: 2}, {Key: "c", Value: 3}}},
+			},
+		},
+		{
+			name: "top-level list",
+			data: "- b: 1\n  a: [{d: 2, c: 3}]\n- [{f: 4, e: 5}]\n",
+			want: []interface{}{
+				yaml.MapSlice{
+					{Key: "b", Value: 1},
+					{Key: "a", Value: []interface{}{yaml.MapSlice{{Key: "d", Value: 2}, {Key: "c", Value: 3}}}},
+				},
+				[]interface{}{yaml.MapSlice{{Key: "f", Value: 4}, {Key: "e", Value: 5}}},
+			},
+		},
+		{name: "scalar", data: "text\n", want: "text"},
+		{name: "empty", data: "", want: yaml.MapSlice{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseYaml([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseYaml() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines printed around each hunk, same default as diff -u.
+const DefaultContextLines = 3
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+// edit is one step of an edit script: an unchanged line, a line deleted
+// from the origin or a line inserted from the destination.
+type edit struct {
+	kind editKind
+	org  int
+	dest int
+}
+
+func splitLines(content []byte) []string {
+	/*
+		Split content into lines, keeping the trailing "\n" on each line so
+		that a missing newline at end of file is seen as a difference.
+	*/
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func myersDiff(a, b []string) []edit {
+	/*
+		Compute the shortest edit script between a and b with the linear
+		space variant of the Myers O((N+M)D) algorithm: the middle of an
+		optimal path is found searching from both ends, then each half is
+		solved the same way. Memory grows with N+M, not with D².
+	*/
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return deletesFirst(d.script)
+}
+
+// differ builds the edit script of a and b, range by range.
+type differ struct {
+	a, b   []string
+	script []edit
+}
+
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.script = append(d.script, edit{kind: editEqual, org: aLo, dest: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aHi > aLo && bHi > bLo && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.script = append(d.script, edit{kind: editInsert, org: aLo, dest: y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.script = append(d.script, edit{kind: editDelete, org: x, dest: bLo})
+		}
+	default:
+		x, y := d.bisect(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+	for i := 0; i < suffix; i++ {
+		d.script = append(d.script, edit{kind: editEqual, org: aHi + i, dest: bHi + i})
+	}
+}
+
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int) {
+	/*
+		Return the point where the forward path from the start and the
+		reverse path from the end of the ranges first overlap, both ranges
+		being non empty without common prefix or suffix.
+	*/
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	v1, v2 := make([]int, size), make([]int, size)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[offset+1], v2[offset+1] = 0, 0
+	delta := n - m
+	// An odd delta makes the forward path overlap first
+	front := delta%2 != 0
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		for k1 := -step + k1start; k1 <= step-k1end; k1 += 2 {
+			i := offset + k1
+			var x1 int
+			if k1 == -step || (k1 != step && v1[i-1] < v1[i+1]) {
+				x1 = v1[i+1]
+			} else {
+				x1 = v1[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && d.a[aLo+x1] == d.b[bLo+y1] {
+				x1++
+				y1++
+			}
+			v1[i] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				j := offset + delta - k1
+				if j >= 0 && j < size && v2[j] != -1 && x1 >= n-v2[j] {
+					return aLo + x1, bLo + y1
+				}
+			}
+		}
+		for k2 := -step + k2start; k2 <= step-k2end; k2 += 2 {
+			i := offset + k2
+			var x2 int
+			if k2 == -step || (k2 != step && v2[i-1] < v2[i+1]) {
+				x2 = v2[i+1]
+			} else {
+				x2 = v2[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && d.a[aHi-x2-1] == d.b[bHi-y2-1] {
+				x2++
+				y2++
+			}
+			v2[i] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				j := offset + delta - k2
+				if j >= 0 && j < size && v1[j] != -1 {
+					x1 := v1[j]
+					y1 := x1 - (j - offset)
+					if x1 >= n-x2 {
+						return aLo + x1, bLo + y1
+					}
+				}
+			}
+		}
+	}
+	// Nothing in common, delete everything then insert everything
+	return aHi, bLo
+}
+
+func deletesFirst(script []edit) []edit {
+	// Print the deleted lines of a change before the inserted ones, as
+	// diff -u does
+	for start := 0; start < len(script); {
+		if script[start].kind == editEqual {
+			start++
+			continue
+		}
+		end := start
+		deleted := 0
+		for end < len(script) && script[end].kind != editEqual {
+			if script[end].kind == editDelete {
+				deleted++
+			}
+			end++
+		}
+		org, dest := script[start].org, script[start].dest
+		for i := start; i < end; i++ {
+			if i-start < deleted {
+				script[i] = edit{kind: editDelete, org: org + i - start, dest: dest}
+			} else {
+				script[i] = edit{kind: editInsert, org: org + deleted, dest: dest + i - start - deleted}
+			}
+		}
+		start = end
+	}
+	return script
+}
+
+func hunkRange(start int, count int) string {
+	// Empty ranges point at the line before the hunk, as diff -u does
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func formatLine(prefix string, line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return prefix + line
+	}
+	return prefix + line + "\n\\ No newline at end of file\n"
+}
+
+func unifiedDiff(a, b []string, orgName string, destName string, context int) []string {
+	/*
+		Return a unified diff of a and b, one entry per output line, or
+		nil when both sides are equal.
+	*/
+	script := myersDiff(a, b)
+	var changes []int
+	for i, e := range script {
+		if e.kind != editEqual {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	output := []string{
+		fmt.Sprintf("--- %s\n", orgName),
+		fmt.Sprintf("+++ %s\n", destName),
+	}
+	for i := 0; i < len(changes); {
+		// Merge changes whose contexts overlap into the same hunk
+		first := changes[i]
+		last := first
+		for i++; i < len(changes) && changes[i]-last <= 2*context+1; i++ {
+			last = changes[i]
+		}
+		start := first - context
+		if start < 0 {
+			start = 0
+		}
+		end := last + context + 1
+		if end > len(script) {
+			end = len(script)
+		}
+
+		var body []string
+		orgStart, destStart := script[start].org, script[start].dest
+		orgCount, destCount := 0, 0
+		for _, e := range script[start:end] {
+			switch e.kind {
+			case editEqual:
+				body = append(body, formatLine(" ", a[e.org]))
+				orgCount++
+				destCount++
+			case editDelete:
+				body = append(body, formatLine("-", a[e.org]))
+				orgCount++
+			case editInsert:
+				body = append(body, formatLine("+", b[e.dest]))
+				destCount++
+			}
+		}
+		output = append(output, fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(orgStart, orgCount), hunkRange(destStart, destCount)))
+		output = append(output, body...)
+	}
+	return output
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want string
+	}{
+		{
+			name: "both empty",
+		},
+		{
+			name: "equal",
+			org:  "a\nb\n",
+			dest: "a\nb\n",
+		},
+		{
+			name: "origin empty",
+			dest: "a\nb\n",
+			want: "--- org\n+++ dest\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "destination empty",
+			org:  "a\n",
+			want: "--- org\n+++ dest\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "missing newline at end of file",
+			org:  "a\nb\n",
+			dest: "a\nb",
+			want: "--- org\n+++ dest\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "modified line",
+			org:  "a\nb\nc\n",
+			dest: "a\nx\nc\n",
+			want: "--- org\n+++ dest\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := unifiedDiff(splitLines([]byte(test.org)), splitLines([]byte(test.dest)), "org", "dest", DefaultContextLines)
+			if got := strings.Join(lines, ""); got != test.want {
+				t.Errorf("unifiedDiff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMyersDiffMinimal(t *testing.T) {
+	a := splitLines([]byte("a\nb\nc\na\nb\nb\na\n"))
+	b := splitLines([]byte("c\nb\na\nb\na\nc\n"))
+	changes := 0
+	for _, e := range myersDiff(a, b) {
+		if e.kind != editEqual {
+			changes++
+		}
+	}
+	if changes != 5 {
+		t.Errorf("myersDiff() has %d changes, want 5", changes)
+	}
+}
+
+func TestMyersDiffDisjoint(t *testing.T) {
+	// Nothing in common is the worst case, D equals N+M
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	script := myersDiff(a, b)
+	if len(script) != len(a)+len(b) {
+		t.Fatalf("myersDiff() has %d edits, want %d", len(script), len(a)+len(b))
+	}
+	for i, e := range script {
+		want := editDelete
+		if i >= len(a) {
+			want = editInsert
+		}
+		if e.kind != want {
+			t.Fatalf("edit %d is %v, want %v", i, e.kind, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestURLDifferences(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		dest string
+		want string
+		urls bool
+	}{
+		{name: "same", org: "rabbit://u:p@h1:5672/", dest: "rabbit://u:p@h1:5672/", urls: true},
+		{name: "hosts reordered", org: "rabbit://u:p@h1:5672,u:p@h2:5672/", dest: "rabbit://u:p@h2:5672,u:p@H1:5672", urls: true},
+		{name: "host changed", org: "rabbit://u:p@h1:5672/", dest: "rabbit://u:p@rabbitmq:5672/", urls: true,
+			want: "hosts changed from h1 to rabbitmq"},
+		{name: "hosts count", org: "rabbit://u:p@h1:5672,u:p@h2:5672/", dest: "rabbit://u:p@rabbitmq:5672/", urls: true,
+			want: "hosts changed from 2 to 1"},
+		{name: "port", org: "rabbit://u:p@h1:5672/", dest: "rabbit://u:p@h1:5671/", urls: true,
+			want: "port changed from 5672 to 5671"},
+		{name: "ipv6 port", org: "rabbit://u:p@[::1]:5672/", dest: "rabbit://u:p@[::1]:5671/", urls: true,
+			want: "port changed from 5672 to 5671"},
+		{name: "scheme and user", org: "mysql://nova:p@db/nova", dest: "mysql+pymysql://nova_api:p@db/nova", urls: true,
+			want: "scheme changed from mysql to mysql+pymysql, user changed from nova to nova_api"},
+		{name: "password", org: "rabbit://u:old@h1:5672/", dest: "rabbit://u:new@h1:5672/", urls: true,
+			want: "password changed"},
+		{name: "password with @", org: "mysql://nova:p@ss@db/nova", dest: "mysql://nova:p@ss2@db/nova", urls: true,
+			want: "password changed"},
+		{name: "database", org: "mysql://nova:p@db/nova", dest: "mysql://nova:p@db/nova_api", urls: true,
+			want: "database changed from /nova to /nova_api"},
+		{name: "virtual host", org: "rabbit://u:p@h1:5672/", dest: "rabbit://u:p@h1:5672/vhost", urls: true,
+			want: "virtual host changed from / to /vhost"},
+		{name: "query", org: "rabbit://u:p@h1/?ssl=0&heartbeat=60", dest: "rabbit://u:p@h1/?ssl=1&timeout=5", urls: true,
+			want: "heartbeat=60 dropped, ssl changed from 0 to 1, timeout=5 added"},
+		{name: "query reordered", org: "mysql://db/nova?a=1&b=2", dest: "mysql://db/nova?b=2&a=1", urls: true},
+		{name: "not a url", org: "rabbit", dest: "rabbit://u:p@h1/"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, urls := urlDifferences(test.org, test.dest)
+			if urls != test.urls || got != test.want {
+				t.Errorf("urlDifferences() = %q, %v, want %q, %v", got, urls, test.want, test.urls)
+			}
+			for _, password := range []string{"old", "new", "ss2"} {
+				if strings.Contains(got, password) {
+					t.Errorf("urlDifferences() = %q shows the password", got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareIniURL(t *testing.T) {
+	f := CompareFileNames{Origin: "org.conf", Destination: "dest.conf"}
+	org := "[DEFAULT]\ntransport_url = rabbit://u:p@h1:5672,u:p@h2:5672/\n"
+	dest := "[DEFAULT]\ntransport_url = rabbit://u:p@h2:5672,u:p@h1:5672/\n"
+	if err := f.compareIni([]byte(org), []byte(dest), f.Origin, f.Destination); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Changes) != 0 {
+		t.Errorf("Changes = %q, want none for reordered hosts", describeChanges(f.Changes))
+	}
+}